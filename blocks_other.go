@@ -0,0 +1,14 @@
+// Copyright 2020 Matthew Holt
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package diskspace
+
+import "os"
+
+// allocatedSize returns the size of the file described
+// by info; allocated space is not known on this platform.
+func allocatedSize(info os.FileInfo) uint64 {
+	return uint64(info.Size())
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 Matthew Holt
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package diskspace
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocatedSize returns the space allocated on disk for
+// the file described by info, which is less than its
+// size for sparse files.
+func allocatedSize(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		// st_blocks is always in 512-byte units
+		return uint64(st.Blocks) * 512
+	}
+	return uint64(info.Size())
+}
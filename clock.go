@@ -0,0 +1,48 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import "time"
+
+// clock tells time and makes timers. Maintainers use it
+// instead of the time package so that tests can control
+// the passage of time.
+type clock interface {
+	now() time.Time
+	newTimer(d time.Duration) clockTimer
+	newTicker(d time.Duration) clockTicker
+}
+
+// clockTimer is the part of *time.Timer used by Maintainer.
+type clockTimer interface {
+	Chan() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// clockTicker is the part of *time.Ticker used by Maintainer.
+type clockTicker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// realClock is the clock of the time package.
+type realClock struct{}
+
+func (realClock) now() time.Time { return time.Now() }
+
+func (realClock) newTimer(d time.Duration) clockTimer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) newTicker(d time.Duration) clockTicker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) Chan() <-chan time.Time { return t.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) Chan() <-chan time.Time { return t.C }
@@ -0,0 +1,146 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ZFSUsage is a UsageProvider for ZFS, whose statfs
+// results count neither snapshots nor space shared with
+// other datasets in the pool. It reads the space
+// accounting of the dataset containing the volume with
+// the zfs command. If the zfs command is not installed,
+// it reads the volume as LocalUsage does.
+type ZFSUsage struct {
+	// If true, space held only by snapshots of the
+	// dataset is left out of its used and total
+	// space, so that ratios reflect live data alone.
+	ExcludeSnapshots bool
+}
+
+// Usage implements UsageProvider.
+func (z ZFSUsage) Usage(ctx context.Context, volume string) (Usage, error) {
+	du, err := LocalUsage{}.Usage(ctx, volume)
+	if err != nil {
+		return du, err
+	}
+	out, err := runTool(ctx, "zfs", "list", "-Hp", "-o", "used,avail,usedbysnapshots", volume)
+	if errors.Is(err, exec.ErrNotFound) {
+		return du, nil
+	}
+	if err != nil {
+		return Usage{}, err
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) != 3 {
+		return Usage{}, fmt.Errorf("unexpected output from zfs list: %q", out)
+	}
+	var nums [3]uint64
+	for i, f := range fields {
+		if nums[i], err = strconv.ParseUint(f, 10, 64); err != nil {
+			return Usage{}, fmt.Errorf("unexpected output from zfs list: %q", out)
+		}
+	}
+	used, avail, snapshots := nums[0], nums[1], nums[2]
+	if z.ExcludeSnapshots && snapshots <= used {
+		used -= snapshots
+	}
+
+	du.Used = used
+	du.Available = avail
+	du.Free = avail
+	du.All = used + avail
+	return du, nil
+}
+
+// BtrfsUsage is a UsageProvider for Btrfs, whose statfs
+// results can be misleading because space is allocated to
+// data and metadata in chunks, and because of RAID
+// profiles. It reads the estimates of used and free space
+// given by the btrfs command, which accounts for them. If
+// the btrfs command is not installed, it reads the volume
+// as LocalUsage does. The btrfs command may need to run as
+// root to report accurately.
+type BtrfsUsage struct{}
+
+// Usage implements UsageProvider.
+func (BtrfsUsage) Usage(ctx context.Context, volume string) (Usage, error) {
+	du, err := LocalUsage{}.Usage(ctx, volume)
+	if err != nil {
+		return du, err
+	}
+	out, err := runTool(ctx, "btrfs", "filesystem", "usage", "-b", volume)
+	if errors.Is(err, exec.ErrNotFound) {
+		return du, nil
+	}
+	if err != nil {
+		return Usage{}, err
+	}
+
+	// the "Overall" section has lines like "Used: 393216000"
+	// and "Free (estimated): 9951535104 (min: 5669150720)"
+	overall := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, seen := overall[key]; seen {
+			continue
+		}
+		if fields := strings.Fields(value); len(fields) > 0 {
+			overall[key] = fields[0]
+		}
+	}
+
+	used, err := strconv.ParseUint(overall["Used"], 10, 64)
+	if err != nil {
+		return Usage{}, fmt.Errorf("unexpected output from btrfs filesystem usage: no used space")
+	}
+	free, err := strconv.ParseUint(overall["Free (estimated)"], 10, 64)
+	if err != nil {
+		return Usage{}, fmt.Errorf("unexpected output from btrfs filesystem usage: no estimated free space")
+	}
+
+	// used space is raw, so with a RAID profile that keeps
+	// more than one copy it must be scaled down to match
+	// the estimate of free space
+	if ratio, err := strconv.ParseFloat(overall["Data ratio"], 64); err == nil && ratio > 1 {
+		used = uint64(float64(used) / ratio)
+	}
+
+	du.Used = used
+	du.Available = free
+	du.Free = free
+	du.All = used + free
+	return du, nil
+}
+
+// runTool runs the named command with args and returns
+// its output, or, if it fails, an error that includes its
+// standard error. If the command is not installed, the
+// error wraps exec.ErrNotFound.
+func runTool(ctx context.Context, name string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return string(out), nil
+}
@@ -4,7 +4,14 @@ package diskspace
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"runtime/debug"
+	"strconv"
 	"sync"
 	"time"
 
@@ -16,128 +23,2157 @@ type Maintainer struct {
 	// The volume to maintain. Default: "/"
 	Volume string
 
+	// A name for the Maintainer, which is added to
+	// every log entry and given to Metrics along
+	// with Volume, to tell several Maintainers
+	// apart at a glance. Default: Device, if set,
+	// or else Volume
+	Name string
+
+	// If set, these volumes are measured instead
+	// of Volume to decide whether to clean, for
+	// when the space that Clean frees is not on
+	// the volume it runs on (or not only there).
+	// Cleaning is triggered when any of them
+	// needs it. Volume still names the maintainer
+	// in logs, metrics and events.
+	MeasureVolumes []string
+
+	// If set, the volume to maintain is wherever
+	// this block device (e.g. "/dev/nvme1n1p1") is
+	// mounted, which is looked up when maintenance
+	// starts. This takes precedence over Volume.
+	Device string
+
+	// If true and Volume is on an overlay file
+	// system, as is usual for the root of a
+	// container, disk usage is read from the
+	// overlay's upper directory, where the
+	// container's writes are stored, instead of
+	// the overlay itself. With a size limit
+	// enforced by project quota (as with Docker's
+	// "--storage-opt size" on XFS), that reports the
+	// container's own limit rather than the host's
+	// disk. The upper directory is usually only
+	// accessible from the host or if bind-mounted
+	// into the container; if it is not accessible,
+	// Volume is read as usual.
+	ContainerAware bool
+
+	// If true, maintenance waits for the volume to
+	// become available (for example, mounted)
+	// before starting, instead of giving up if it
+	// does not exist.
+	WaitForVolume bool
+
 	// How often to check disk space usage.
 	// Default: 10m
 	CheckInterval time.Duration
 
+	// If set, checks happen at the times given by
+	// this cron expression (e.g. "0 2 * * *" for
+	// 2am daily, in local time) instead of every
+	// CheckInterval, which suits cleanups that
+	// should only run during quiet hours. There is
+	// no initial check, and backoff, AdaptiveInterval
+	// and Jitter do not apply. Schedule and
+	// CheckInterval are mutually exclusive.
+	Schedule string
+
+	// If true, checks happen more often as usage
+	// approaches Threshold, down to MinInterval,
+	// and relax back toward CheckInterval as
+	// usage falls.
+	AdaptiveInterval bool
+
+	// The shortest interval between checks when
+	// AdaptiveInterval is enabled. It is never
+	// longer than CheckInterval. Default: 1m
+	MinInterval time.Duration
+
+	// The maximum random offset applied to each
+	// CheckInterval, so that many instances started
+	// at once do not all check at the same time.
+	// Must be less than CheckInterval; larger
+	// values are reduced to half of it.
+	Jitter time.Duration
+
+	// If true, the initial check is delayed by a
+	// random duration of up to Jitter.
+	JitterInitialCheck bool
+
+	// How long to wait before the initial check,
+	// for example to let other services finish
+	// starting up. Set it to CheckInterval to
+	// skip the initial check and wait for the
+	// first regular one. Default: 0 (check
+	// immediately)
+	StartupDelay time.Duration
+
+	// If set, Maintain and Run stop on their own
+	// after running this long, as if their context
+	// had been cancelled, which suits short-lived
+	// jobs. The context passed in can still stop
+	// them sooner. Default: 0 (run until the
+	// context is cancelled)
+	MaxDuration time.Duration
+
+	// The maximum interval between checks while
+	// disk usage cannot be read. Each consecutive
+	// failure doubles the interval, up to this
+	// value; a successful read resets it to
+	// CheckInterval. Default: 0 (no backoff)
+	MaxBackoff time.Duration
+
+	// If true, a volume that is mounted read-only
+	// never triggers cleaning, since Clean cannot
+	// free any space on it.
+	SkipReadOnly bool
+
+	// How many more times to try reading disk
+	// usage during a check if it fails, which
+	// helps on flaky network file systems.
+	// Default: 0
+	CheckRetries int
+
+	// How long to wait between retries of
+	// reading disk usage. Default: 1s
+	CheckRetryDelay time.Duration
+
+	// The minimum time between a call to
+	// CheckNow and the previous check. If a
+	// call comes sooner, it returns ErrTooSoon
+	// without checking. Scheduled checks are
+	// not limited. Default: 0 (no limit)
+	MinCheckInterval time.Duration
+
+	// If true, a failure to read disk usage stops
+	// Run with that error, instead of being logged
+	// and retried at the next check.
+	FatalOnCheckError bool
+
+	// If true, an error from Clean stops Run with
+	// that error, instead of being logged and
+	// retried at the next check, so that a
+	// supervisor can restart the process.
+	FatalOnCleanError bool
+
 	// The ratio of used/total space before
 	// disk cleaning. Default: 0.9
 	Threshold float64
 
+	// The same as Threshold, but as a whole
+	// percentage (e.g. 90 for 0.9), which is
+	// how thresholds are often written in
+	// configuration. Only one of Threshold and
+	// ThresholdPercent may be set.
+	ThresholdPercent int
+
+	// If set, the ratio of used/total space that is
+	// compared with Threshold is an exponentially
+	// weighted moving average of readings rather than
+	// the latest reading, which keeps cleaning from
+	// flapping on and off when usage hovers around the
+	// threshold. This is the weight of each new reading,
+	// between 0 and 1: smaller values smooth more, but
+	// react more slowly. The first reading starts the
+	// average, so early checks are dominated by a few raw
+	// readings until it warms up. It restarts from the
+	// reading after each clean. Default: 0 (no smoothing)
+	Smoothing float64
+
+	// How the ratio of used/total space is
+	// computed; see UsageBasis. This is the same
+	// on every platform. Default: BasisAvailable
+	//
+	// Note that the default used to depend on the
+	// platform: macOS was available-based, while
+	// other systems were free-based. Set BasisFree
+	// to keep the old numbers on those systems.
+	UsageBasis UsageBasis
+
+	// Deprecated: Usage is based on available space
+	// by default; see UsageBasis.
+	UseAvailable bool
+
+	// The ratio of used/total inodes before
+	// disk cleaning. If set, disk cleaning is also
+	// triggered when inode usage exceeds this,
+	// regardless of Threshold. Volumes that do
+	// not report inode counts are ignored.
+	InodeThreshold float64
+
+	// The ratio of used/total space above which
+	// a warning is logged (and an event emitted),
+	// without cleaning. It should be lower than
+	// Threshold. Default: 0 (no alerts)
+	AlertThreshold float64
+
+	// If set, a warning is logged (and a UsageSpike
+	// event emitted) when the used ratio grows by
+	// more than this much from one check to the
+	// next (e.g. 0.2 for 20 percentage points),
+	// regardless of how full the disk is. Sudden
+	// growth often means something is running
+	// away. Default: 0 (no spike detection)
+	SpikeThreshold float64
+
+	// The minimum number of bytes that should
+	// be available on the volume. If set, disk
+	// cleaning is also triggered when available
+	// space drops below this amount, regardless
+	// of Threshold.
+	MinFree uint64
+
+	// The minimum ratio of available/total space
+	// (e.g. 0.15 to keep 15% available). If set,
+	// disk cleaning is also triggered when the
+	// available ratio drops below it, regardless
+	// of Threshold. This is not simply the
+	// complement of Threshold: on file systems
+	// with reserved blocks, the used and
+	// available ratios do not add up to 1.
+	MinFreeRatio float64
+
 	// The function that will be called to
-	// clean up disk space.
-	Clean func() error
+	// clean up disk space. The context is
+	// the one passed into Maintain; if it is
+	// cancelled while Clean is running, Clean
+	// should abort promptly.
+	Clean func(ctx context.Context) error
+
+	// Like Clean, but is also given the number of
+	// bytes that need to be freed to bring usage
+	// below Threshold (or LowWaterMark, if set)
+	// and available space above MinFree, so that
+	// it can delete just enough. If set, Clean is
+	// ignored.
+	CleanBytes func(ctx context.Context, needed uint64) error
+
+	// Cleaners to run in order, escalating from
+	// one to the next while disk usage still
+	// requires cleaning, re-checking usage after
+	// each. If set, Clean is ignored.
+	Cleaners []Cleaner
+
+	// Rules that each run their own cleaner when
+	// disk usage reaches their threshold, in
+	// addition to Clean (which is optional if
+	// Rules are set). Unlike Cleaners, rules do
+	// not escalate; each whose threshold is met
+	// runs once per check, highest first.
+	Rules []Rule
+
+	// If set, it decides whether cleaning is
+	// needed, given the current usage. It takes
+	// precedence over Threshold, MinFree,
+	// MinFreeRatio, InodeThreshold and
+	// LowWaterMark, which are then ignored,
+	// except to estimate how much to free.
+	TriggerFunc func(Usage) bool
+
+	// If set, it is called when cleaning is
+	// needed, and cleaning only proceeds if it
+	// returns true. Use it to veto cleaning at
+	// times when it would be unsafe, such as
+	// during a backup.
+	ShouldClean func(Usage) bool
+
+	// If set, BeforeClean is called with the usage
+	// right before cleaning, and AfterClean with the
+	// usage before and after it, even if cleaning
+	// failed; for example, to pause writes while
+	// cleaning. If BeforeClean returns an error,
+	// cleaning is skipped until the next check and
+	// the error is returned from the check.
+	BeforeClean func(Usage) error
+	AfterClean  func(before, after Usage)
+
+	// If true, checks run as usual, but instead of
+	// cleaning, the clean that would have run is
+	// logged (and a WouldClean event emitted).
+	// Useful for tuning thresholds safely.
+	DryRun bool
+
+	// If true, m only monitors: checks run, and
+	// usage is logged and reported to Metrics,
+	// Events and the other hooks, including
+	// warnings when thresholds are crossed, but
+	// nothing is ever cleaned. No Clean function
+	// is needed.
+	MonitorOnly bool
+
+	// If set, Clean will be called repeatedly
+	// until the ratio of used/total space drops
+	// below this value, Clean returns an error,
+	// or Clean fails to free any space. It
+	// should be lower than Threshold.
+	LowWaterMark float64
+
+	// The minimum number of bytes a cleaning
+	// cycle must free to be considered effective.
+	// If less is freed, the check fails with
+	// ErrNoSpaceFreed. Default: 1
+	MinFreedBytes uint64
+
+	// The minimum number of bytes a single clean
+	// must free to count as effective. Cleans that
+	// free less are logged as a warning and counted
+	// in Stats.IneffectiveCleans, which can reveal a
+	// cleaner that is wearing out before it stops
+	// freeing anything at all. Default: 0 (every
+	// clean is effective)
+	MinEffectiveFreedBytes uint64
+
+	// The maximum number of cleaner calls per
+	// check, which matters mostly when
+	// LowWaterMark is set. Default: 10
+	MaxCleanAttempts int
+
+	// The most bytes to free in one check. Once
+	// cleaning has freed at least this much, it
+	// stops, even if usage is still above
+	// LowWaterMark, which guards against a noisy
+	// reading causing Clean to delete far more
+	// than needed. Default: 0 (no limit)
+	MaxFreePerCycle uint64
+
+	// A floor on used space, in bytes or as a
+	// ratio of the volume's size, below which no
+	// further cleaner calls are made in a check,
+	// even if usage is still above LowWaterMark.
+	// This guards against an overly aggressive
+	// cleaner deleting everything. Default: 0
+	// (no floor)
+	MinRetainBytes uint64
+	MinRetainRatio float64
+
+	// The minimum time between the end of one
+	// successful clean and the start of the next.
+	// Checks during the cooldown do not trigger
+	// Clean, even if still above threshold.
+	// Default: 0 (no cooldown)
+	CleanCooldown time.Duration
+
+	// The maximum duration of a single call to
+	// Clean. If Clean does not return in time,
+	// the check fails with a timeout error and
+	// maintenance continues without waiting for
+	// it. Default: 0 (no timeout)
+	CleanTimeout time.Duration
+
+	// What reads the disk usage of volumes, such
+	// as one that reads it from a remote host.
+	// Default: LocalUsage, unless UsageFunc or
+	// QuotaProject is set
+	UsageProvider UsageProvider
+
+	// The function used to read the disk usage
+	// of a volume. Mostly useful for testing.
+	// Ignored if UsageProvider is set.
+	UsageFunc func(path string) (Usage, error)
+
+	// If set, disk usage is measured against this
+	// project quota (Linux only) on the volume's
+	// file system, instead of the file system as
+	// a whole. See ProjectQuotaUsage. Ignored if
+	// UsageProvider or UsageFunc is set.
+	QuotaProject uint32
+
+	// The maximum time to wait for disk usage to
+	// be read, after which the reading fails with
+	// a timeout error. This keeps an unresponsive
+	// file system, such as a stalled NFS mount,
+	// from stalling maintenance indefinitely. The
+	// stalled reading is abandoned, not stopped.
+	// Default: 0 (no timeout)
+	StatfsTimeout time.Duration
+
+	// If set, OnMaxFailures is called when Clean
+	// has failed this many times in a row, as an
+	// escalation hook (for example, to page
+	// someone). It is called again only after
+	// Clean succeeds and then fails this many
+	// times in a row again.
+	MaxConsecutiveFailures int
+
+	// Called with the latest error when Clean has
+	// failed MaxConsecutiveFailures times in a row.
+	OnMaxFailures func(err error)
+
+	// If set, called after every successful reading
+	// of disk usage, whether or not cleaning follows.
+	// It runs synchronously while checks are locked
+	// out, so it should return quickly.
+	OnCheck func(Usage)
+
+	// If set, called with the error whenever disk
+	// usage cannot be read during a check, such as
+	// when the volume is unmounted, but not when
+	// Clean fails; a cleaner failure can then be
+	// treated differently from a failure to read,
+	// which is often transient. Errors are still
+	// logged. Like OnCheck, it runs synchronously
+	// while checks are locked out.
+	OnCheckError func(error)
+
+	// If set, events are sent on this channel as
+	// maintenance happens. Sends never block: if
+	// the channel is full or nobody is receiving,
+	// the event is dropped, so use a buffered
+	// channel to avoid missing events.
+	Events chan<- Event
+
+	// If set, a JSON description of the volume and
+	// its usage is posted to this URL when usage
+	// exceeds the threshold and when Clean fails.
+	// The payload's "text" field summarizes it, so
+	// it can be an incoming webhook of a chat
+	// service like Slack. Posts happen in the
+	// background and never delay maintenance; a
+	// failure to post is only logged.
+	Webhook string
+
+	// If set, the outcome of every check is written
+	// here as a line of JSON, including the time,
+	// volume, usage, and whether any space was
+	// cleaned. Each line is a single Write call.
+	// Write errors are logged but do not affect
+	// maintenance.
+	ResultWriter io.Writer
+
+	// Optional metrics recorder.
+	Metrics Metrics
 
 	// Custom logger.
 	Logger *zap.Logger
 
-	mu sync.Mutex
+	// Custom logger from the standard library.
+	// Used only if Logger is nil.
+	SlogLogger *slog.Logger
+
+	// Custom log function, for other logging
+	// libraries. Used only if Logger and
+	// SlogLogger are nil.
+	LogFunc LogFunc
+
+	// The number of decimal places of percentages
+	// in logs (the used_percent field) and from
+	// UsedPercent. For whole percentages, use
+	// WithPercentPrecision(0). Default: 1
+	PercentPrecision int
+
+	// If true, routine messages (startup and
+	// successful cleans) are logged at Debug level,
+	// leaving only warnings and errors at higher
+	// levels.
+	QuietSuccess bool
+
+	mu           sync.Mutex
+	defaultsOnce sync.Once
+
+	// guards stats and freedHist, so that they can be
+	// read without waiting for a check to finish;
+	// writers also hold mu. Also held when Threshold,
+	// Volume (once set from Device) or overlayUpper
+	// change, for the same reason.
+	statsMu sync.Mutex
+
+	stats     Stats
+	lastClean time.Time
+	lastUsage Usage
+	trend     trend
+	freedHist [7]uint64 // one per freedBuckets, plus overflow
+
+	// whether the read-only notice has been logged
+	readOnlyNoticed bool
+
+	// consecutive failures to read disk usage
+	checkFailures int
+
+	// which of MeasureVolumes was read last
+	measuredVolume string
+
+	// whether the rest of the check in progress
+	// re-reads only measuredVolume
+	measurePinned bool
+
+	// the overlay upper dir to read instead of
+	// Volume, if ContainerAware
+	overlayUpper string
+
+	// whether Device and overlayUpper have been
+	// resolved
+	volumeResolved bool
+
+	// whether PercentPrecision was set by
+	// WithPercentPrecision, so that 0 is not unset
+	percentPrecisionSet bool
+
+	// moving average of the used ratio, if Smoothing
+	// is set; zero until the first reading
+	smoothedRatio float64
+
+	// source of time; replaceable in tests
+	clock clock
+
+	// the parsed Schedule, if set
+	schedule *schedule
+
+	// posts to Webhook, if set
+	webhook *webhook
+
+	// signals Run that CheckInterval changed
+	intervalChanged chan struct{}
+
+	// when CheckNow last started a check
+	lastCheckNow time.Time
+
+	// cleans running in their own goroutines
+	inflight sync.WaitGroup
+
+	// set by Manager if other maintainers share
+	// the same file system
+	shared *sharedFileSystem
+
+	// lifecycle of the goroutine started by Start
+	runMu  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 // Maintain maintains disk space. It checks the disk usage
 // for m.Volume every m.CheckInterval, and runs m.Clean if
-// the disk usage is above m.Threshold. If m.Clean is nil,
-// this function panics. Otherwise, it blocks indefinitely
-// until ctx is cancelled.
+// the disk usage is above m.Threshold. It blocks until
+// ctx is cancelled or Run returns an error, which is
+// logged; so if m has nothing to clean with and is not
+// MonitorOnly, it logs that and returns right away. A
+// clean that is in progress when ctx is cancelled will
+// observe the cancellation through the context it was
+// given, and Maintain does not return until it has.
 func (m *Maintainer) Maintain(ctx context.Context) {
-	if m.Clean == nil {
-		panic("nil Clean function")
-	}
-	if m.Volume == "" {
-		m.Volume = defaultVolume
+	err := m.Run(ctx)
+	if err != nil {
+		m.Logger.Error("not maintaining disk space",
+			zap.String("volume", m.volume()),
+			zap.Error(err))
 	}
-	if m.Threshold <= 0 || m.Threshold >= 1 {
-		m.Threshold = defaultThreshold
+}
+
+// Run is like Maintain, but returns an error instead of
+// logging it. It returns nil when ctx is cancelled or
+// m.MaxDuration elapses.
+//
+// Run does not return until any check or clean in
+// progress has finished, even one started by CheckNow or
+// one that exceeded CleanTimeout, so that when it returns,
+// no cleanup is running. Cleans are expected to return
+// promptly once their context is cancelled; a clean that
+// ignores its context delays the return of Run. It
+// returns an error if m is misconfigured, if m.Volume does
+// not exist (unless m.WaitForVolume is set), or if disk
+// usage cannot be read while m.FatalOnCheckError is set,
+// or if Clean fails while m.FatalOnCleanError is set.
+func (m *Maintainer) Run(ctx context.Context) error {
+	// set defaults first, so that there is a logger
+	// for Maintain to report a misconfiguration with
+	m.defaultsOnce.Do(m.setDefaults)
+	if !m.canRun() {
+		return fmt.Errorf("nil Clean function")
 	}
-	if m.CheckInterval <= 0 {
-		m.CheckInterval = defaultCheckInterval
+	if m.Schedule != "" {
+		sched, err := parseSchedule(m.Schedule)
+		if err != nil {
+			return err
+		}
+		m.mu.Lock()
+		m.schedule = sched
+		m.mu.Unlock()
 	}
-	if m.Logger == nil {
-		m.Logger = zap.NewNop()
+	defer m.shutdown(m.clock.now())
+
+	if m.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.MaxDuration)
+		defer cancel()
 	}
 
-	m.Logger.Info("starting disk usage maintenance goroutine",
-		zap.String("volume", m.Volume),
-		zap.Float64("threshold", m.Threshold),
-		zap.Duration("interval", m.CheckInterval))
+	// these may be changed concurrently, by SetThreshold
+	// and SetCheckInterval
+	m.mu.Lock()
+	volume, threshold, interval := m.Volume, m.Threshold, m.CheckInterval
+	m.mu.Unlock()
+
+	m.infoLogger()("starting disk usage maintenance goroutine",
+		zap.String("volume", volume),
+		zap.Float64("threshold", threshold),
+		zap.Uint64("min_free", m.MinFree),
+		zap.Duration("interval", interval),
+		zap.String("schedule", m.Schedule))
 
-	// initial maintenance
-	err := m.maintainDiskUsage()
+	err := m.waitForVolume(ctx)
 	if err != nil {
-		m.Logger.Error("checking disk space", zap.Error(err))
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	// optionally delay and stagger the initial maintenance
+	initialDelay := m.StartupDelay
+	if m.JitterInitialCheck && m.Jitter > 0 {
+		initialDelay += time.Duration(rand.Int63n(int64(m.Jitter) + 1))
+	}
+	if initialDelay > 0 {
+		delay := m.clock.newTimer(initialDelay)
+		select {
+		case <-delay.Chan():
+		case <-ctx.Done():
+			delay.Stop()
+			return nil
+		}
+	}
+
+	// initial maintenance, unless checks
+	// happen only on schedule
+	if m.Schedule == "" {
+		err = m.tick(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
-	// start maintenance ticker
-	ticker := time.NewTicker(m.CheckInterval)
+	// start maintenance timer
+	timer := m.clock.newTimer(m.nextInterval())
 
 	// maintain until context is canceled
 	for {
 		select {
-		case <-ticker.C:
-			err := m.maintainDiskUsage()
+		case <-timer.Chan():
+			err := m.tick(ctx)
 			if err != nil {
-				m.Logger.Error("checking disk space", zap.Error(err))
-				continue
+				timer.Stop()
+				return err
 			}
+			timer.Reset(m.nextInterval())
+		case <-m.intervalChanged:
+			if !timer.Stop() {
+				select {
+				case <-timer.Chan():
+				default:
+				}
+			}
+			timer.Reset(m.nextInterval())
 		case <-ctx.Done():
-			ticker.Stop()
-			return
+			timer.Stop()
+			return nil
 		}
 	}
 }
 
-func (m *Maintainer) maintainDiskUsage() error {
-	// don't allow maintenance ops to overlap
+// infoLogger returns the function used to log routine
+// messages, which is Debug if QuietSuccess is set.
+func (m *Maintainer) infoLogger() func(string, ...zap.Field) {
+	if m.QuietSuccess {
+		return m.Logger.Debug
+	}
+	return m.Logger.Info
+}
+
+// shutdown waits for any check or clean in progress, then
+// logs a summary of m's activity since started, and lets
+// m.Metrics finalize the volume's measurements.
+func (m *Maintainer) shutdown(started time.Time) {
+	m.waitForCleans()
+
+	stats := m.Stats()
+	m.Logger.Info("stopped disk usage maintenance",
+		zap.String("volume", m.volume()),
+		zap.Uint64("total_cleans", stats.TotalCleans),
+		zap.String("total_freed", FormatBytes(stats.TotalFreedBytes)),
+		zap.Duration("uptime", m.clock.now().Sub(started)))
+
+	if closer, ok := m.Metrics.(VolumeCloser); ok {
+		closer.CloseVolume(m.volume(), m.Name)
+	}
+}
+
+// waitForCleans blocks until no check is in progress and
+// every clean has returned, including cleans that were
+// abandoned after exceeding CleanTimeout.
+func (m *Maintainer) waitForCleans() {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.mu.Unlock()
+	m.inflight.Wait()
+}
+
+// tick performs one scheduled check. Errors are logged,
+// and only those that should stop maintenance are returned.
+func (m *Maintainer) tick(ctx context.Context) error {
+	_, err := m.maintainDiskUsage(ctx)
+	if err == nil {
+		return nil
+	}
+	m.Logger.Error("checking disk space", zap.Error(err))
+	var ce checkError
+	if m.FatalOnCheckError && errors.As(err, &ce) {
+		return err
+	}
+	var cle cleanError
+	if m.FatalOnCleanError && errors.As(err, &cle) {
+		return err
+	}
+	return nil
+}
+
+// waitForVolume returns an error if the disk usage of
+// m.Volume cannot be read. If m.WaitForVolume is true, it
+// instead retries with increasing delays until it can be
+// read or ctx is cancelled.
+func (m *Maintainer) waitForVolume(ctx context.Context) error {
+	delay := time.Second
+	for {
+		err := m.resolveVolume()
+		if err == nil {
+			_, err = m.readVolumes(ctx)
+		}
+		if err == nil {
+			return nil
+		}
+		if !m.WaitForVolume {
+			return volumeError{m.volume(), err}
+		}
+
+		m.Logger.Warn("waiting for volume",
+			zap.String("volume", m.volume()),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+
+		timer := m.clock.newTimer(delay)
+		select {
+		case <-timer.Chan():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		if delay *= 2; delay > m.CheckInterval {
+			delay = m.CheckInterval
+		}
+	}
+}
 
-	du, err := diskUsage(m.Volume)
+// resolveDevice sets m.Volume to the mount point
+// of m.Device, if set.
+func (m *Maintainer) resolveDevice() error {
+	if m.Device == "" {
+		return nil
+	}
+	mountPoint, err := MountPointForDevice(m.Device)
 	if err != nil {
 		return err
 	}
-	totalMB := du.all / MB
-	usedMB := du.used / MB
-	usedRatio := float64(usedMB) / float64(totalMB)
+	m.mu.Lock()
+	m.statsMu.Lock()
+	m.Volume = mountPoint
+	m.statsMu.Unlock()
+	m.mu.Unlock()
+	return nil
+}
+
+// resolveVolume resolves m.Device and then the overlay
+// upper directory of m.Volume, whichever are set.
+func (m *Maintainer) resolveVolume() error {
+	if err := m.resolveDevice(); err != nil {
+		return err
+	}
+	m.resolveOverlay()
+	m.mu.Lock()
+	m.statsMu.Lock()
+	m.volumeResolved = true
+	m.statsMu.Unlock()
+	m.mu.Unlock()
+	return nil
+}
 
-	// nothing to do if disk is not nearly full
-	if usedRatio < m.Threshold {
+// ensureVolume resolves m.Device and the overlay upper
+// directory, if set and not yet resolved, so that usage
+// is read from where m.Volume really is rather than
+// from the default volume or the overlay itself.
+func (m *Maintainer) ensureVolume() error {
+	if m.Device == "" && !m.ContainerAware {
+		return nil
+	}
+	m.statsMu.Lock()
+	resolved := m.volumeResolved
+	m.statsMu.Unlock()
+	if resolved {
 		return nil
 	}
+	return m.resolveVolume()
+}
+
+// resolveOverlay finds the upper directory of m.Volume,
+// if m.ContainerAware is set and it is on an overlay file
+// system. If it cannot, m.Volume is read as usual.
+func (m *Maintainer) resolveOverlay() {
+	if !m.ContainerAware {
+		return
+	}
+	volume := m.volume()
+	upper, err := overlayUpperDir(volume)
+	if err == nil && upper != "" {
+		_, err = os.Stat(upper)
+	}
+	if err != nil {
+		m.Logger.Warn("cannot read overlay upper directory; reading volume instead",
+			zap.String("volume", volume),
+			zap.String("upper_dir", upper),
+			zap.Error(err))
+		upper = ""
+	}
+	m.mu.Lock()
+	m.statsMu.Lock()
+	m.overlayUpper = upper
+	m.statsMu.Unlock()
+	m.mu.Unlock()
+}
+
+// usagePath returns the path to read the disk usage of
+// m.Volume from. m.mu need not be held.
+func (m *Maintainer) usagePath() string {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	if m.overlayUpper != "" {
+		return m.overlayUpper
+	}
+	return m.Volume
+}
+
+// volume returns m.Volume, which may be set from Device
+// at any time. m.mu need not be held.
+func (m *Maintainer) volume() string {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	return m.Volume
+}
+
+// nextInterval returns how long to wait until the next
+// check: until the next time in Schedule, if set, or else
+// CheckInterval, doubled for each consecutive
+// failure to read disk usage (up to MaxBackoff) or
+// shortened as usage nears the threshold if
+// AdaptiveInterval is enabled, then randomly offset by
+// up to ±Jitter.
+func (m *Maintainer) nextInterval() time.Duration {
+	m.mu.Lock()
+	if m.schedule != nil {
+		now := m.clock.now()
+		m.mu.Unlock()
+		return m.schedule.next(now).Sub(now)
+	}
+	interval := m.CheckInterval
+	if m.checkFailures > 0 && m.MaxBackoff > interval {
+		for i := 0; i < m.checkFailures && interval < m.MaxBackoff; i++ {
+			interval *= 2
+		}
+		if interval > m.MaxBackoff {
+			interval = m.MaxBackoff
+		}
+	} else if m.checkFailures == 0 && m.AdaptiveInterval {
+		interval = m.adaptiveInterval(m.stats.LastUsedRatio)
+	}
+	jitter := m.Jitter
+	m.mu.Unlock()
+
+	if jitter <= 0 {
+		return interval
+	}
+	offset := time.Duration(rand.Int63n(2*int64(jitter)+1)) - jitter
+	if interval+offset <= 0 {
+		return interval
+	}
+	return interval + offset
+}
+
+// adaptiveInterval scales the check interval by how
+// close ratio is to the threshold:
+//
+//	CheckInterval × (1 − (ratio/Threshold)²)
+//
+// clamped to [MinInterval, CheckInterval]. Low usage
+// keeps the interval near CheckInterval, and it falls
+// off quickly as usage approaches the threshold. m.mu
+// must be held.
+func (m *Maintainer) adaptiveInterval(ratio float64) time.Duration {
+	closeness := ratio / m.Threshold
+	if closeness > 1 {
+		closeness = 1
+	}
+	interval := time.Duration(float64(m.CheckInterval) * (1 - closeness*closeness))
+	if interval < m.MinInterval {
+		interval = m.MinInterval
+	}
+	return interval
+}
+
+// SetThreshold changes m.Threshold while m may be
+// running. The new threshold applies from the next
+// check. It must be between 0 and 1, exclusive.
+func (m *Maintainer) SetThreshold(threshold float64) error {
+	if threshold <= 0 || threshold >= 1 {
+		return fmt.Errorf("threshold must be between 0 and 1, exclusive: %v", threshold)
+	}
+	m.defaultsOnce.Do(m.setDefaults)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	m.Threshold = threshold
+	return nil
+}
+
+// SetCheckInterval changes m.CheckInterval while m may
+// be running. A running maintainer reschedules its next
+// check right away to match the new interval.
+func (m *Maintainer) SetCheckInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("check interval must be positive: %s", interval)
+	}
+	m.defaultsOnce.Do(m.setDefaults)
+	m.mu.Lock()
+	m.CheckInterval = interval
+	if m.MinInterval > interval {
+		m.MinInterval = interval
+	}
+	if m.Jitter >= interval {
+		m.Jitter = interval / 2
+	}
+	m.mu.Unlock()
+
+	// wake the maintenance loop, if any, unless
+	// it has already been woken
+	select {
+	case m.intervalChanged <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start runs Maintain in a new goroutine until Stop is
+// called or Maintain returns on its own. Calling Start
+// while already running does nothing.
+func (m *Maintainer) Start() {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+	if m.running() {
+		return
+	}
+	if m.cancel != nil {
+		// Maintain returned without Stop being called
+		m.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	m.cancel, m.done = cancel, done
+	go func() {
+		defer close(done)
+		m.Maintain(ctx)
+	}()
+}
+
+// Stop stops the goroutine started by Start and blocks
+// until it, including any clean in progress, returns.
+// Calling Stop while not running does nothing.
+func (m *Maintainer) Stop() {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	<-m.done
+	m.cancel, m.done = nil, nil
+}
+
+// IsRunning returns true if m was started by Start
+// and has neither been stopped nor returned on its own
+// (such as after MaxDuration).
+func (m *Maintainer) IsRunning() bool {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+	return m.running()
+}
+
+// running returns true if the goroutine started by
+// Start has not returned. m.runMu must be held.
+func (m *Maintainer) running() bool {
+	if m.done == nil {
+		return false
+	}
+	select {
+	case <-m.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Cleaner is one step of escalating disk cleanup.
+type Cleaner struct {
+	// The function that cleans up disk space.
+	Clean func(ctx context.Context) error
+
+	// Like Clean, but is told how many bytes need
+	// to be freed. If set, Clean is ignored.
+	CleanBytes func(ctx context.Context, needed uint64) error
+
+	// If set, Clean runs only if the ratio of
+	// used/total space is at least this value,
+	// so that expensive cleaners run only when
+	// cheaper ones have not freed enough.
+	Threshold float64
+}
+
+// cleanFunc returns the function that runs c, given
+// the number of bytes that need to be freed.
+func (c Cleaner) cleanFunc(needed uint64) func(context.Context) error {
+	if c.CleanBytes == nil {
+		return c.Clean
+	}
+	return func(ctx context.Context) error {
+		return c.CleanBytes(ctx, needed)
+	}
+}
+
+// CheckResult describes the outcome of a single check.
+// Byte counts refer to used space on the volume.
+type CheckResult struct {
+	UsedBefore, UsedAfter, Freed uint64
+
+	// Whether the threshold was exceeded
+	// and Clean ran successfully.
+	Cleaned bool
+}
+
+// CheckNow immediately checks disk usage and cleans if
+// necessary, just like a single tick of Maintain. It
+// does not overlap with checks performed by Maintain.
+// If m.MinCheckInterval has not passed since the last
+// check, it returns ErrTooSoon.
+func (m *Maintainer) CheckNow() (CheckResult, error) {
+	if !m.canRun() {
+		return CheckResult{}, fmt.Errorf("nil Clean function")
+	}
+	m.defaultsOnce.Do(m.setDefaults)
+	if err := m.ensureVolume(); err != nil {
+		return CheckResult{}, err
+	}
+	if m.MinCheckInterval > 0 && !m.reserveCheckNow() {
+		return CheckResult{}, ErrTooSoon
+	}
+	return m.maintainDiskUsage(context.Background())
+}
+
+// CleanIfNeeded checks the disk usage of volume once,
+// and if the ratio of used/total space is at or above
+// threshold, runs clean once, without starting any
+// goroutine to maintain it. It suits cron jobs and other
+// short-lived processes. It reports whether clean ran
+// and how many bytes were freed; if clean ran but freed
+// nothing, the error is ErrNoSpaceFreed. For more control,
+// configure a Maintainer and call its CheckNow method.
+func CleanIfNeeded(volume string, threshold float64, clean func() error) (cleaned bool, freed uint64, err error) {
+	if clean == nil {
+		return false, 0, fmt.Errorf("nil Clean function")
+	}
+	if threshold <= 0 || threshold >= 1 {
+		return false, 0, fmt.Errorf("threshold must be between 0 and 1, exclusive: %v", threshold)
+	}
+	m := &Maintainer{
+		Volume:    volume,
+		Threshold: threshold,
+		Clean:     func(context.Context) error { return clean() },
+	}
+	result, err := m.CheckNow()
+	return result.Cleaned, result.Freed, err
+}
 
-	m.Logger.Warn("disk space usage above threshold",
-		zap.Uint64("total_mb", totalMB),
-		zap.Uint64("used_mb", usedMB),
-		zap.Float64("used_ratio", usedRatio),
-		zap.Float64("used_threshold", m.Threshold))
+// reserveCheckNow returns true if enough time has passed
+// since the last check for CheckNow to start another,
+// and if so, records that it has.
+func (m *Maintainer) reserveCheckNow() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	last := m.stats.LastCheck
+	if m.lastCheckNow.After(last) {
+		last = m.lastCheckNow
+	}
+	if !last.IsZero() && m.clock.now().Sub(last) < m.MinCheckInterval {
+		return false
+	}
+	m.lastCheckNow = m.clock.now()
+	return true
+}
 
-	// run cleaner function
-	err = m.Clean()
+// UsedRatio returns the current ratio of used/total space
+// on m.Volume, computed the same way as during checks. It
+// reads the disk directly, never cleans, and does not
+// wait for a check in progress. If m.MeasureVolumes is
+// set, it returns the highest ratio among them. It
+// returns an error if a volume reports no size.
+func (m *Maintainer) UsedRatio() (float64, error) {
+	m.defaultsOnce.Do(m.setDefaults)
+	if err := m.ensureVolume(); err != nil {
+		return 0, err
+	}
+	usages, err := m.readVolumes(context.Background())
 	if err != nil {
-		return fmt.Errorf("clean: %v", err)
+		return 0, err
 	}
+	var ratio float64
+	for _, du := range usages {
+		if du.All == 0 {
+			return 0, errNoSize
+		}
+		if r := m.usedRatio(du); r > ratio {
+			ratio = r
+		}
+	}
+	return ratio, nil
+}
 
-	// see how much space is now available
-	du, err = diskUsage(m.Volume)
+// UsedPercent is like UsedRatio, but returns the usage
+// as a percentage formatted with m.PercentPrecision
+// decimal places, such as "90.1%".
+func (m *Maintainer) UsedPercent() (string, error) {
+	ratio, err := m.UsedRatio()
 	if err != nil {
-		return err
+		return "", err
 	}
-	newUsedMB := du.used / MB
-	usedDiff := usedMB - newUsedMB
+	return m.formatPercent(ratio), nil
+}
 
-	m.Logger.Info("disk space cleaned",
-		zap.Uint64("used_mb", newUsedMB),
-		zap.Uint64("freed_mb", usedDiff))
+// formatPercent formats ratio as a percentage.
+func (m *Maintainer) formatPercent(ratio float64) string {
+	return strconv.FormatFloat(ratio*100, 'f', m.PercentPrecision, 64) + "%"
+}
 
-	return nil
+// WaitUntilBelow blocks until the ratio of used/total
+// space on m.Volume is below ratio, polling every
+// m.CheckInterval. It never cleans. It returns ctx's
+// error if ctx is cancelled first, or an error if disk
+// usage cannot be read.
+func (m *Maintainer) WaitUntilBelow(ctx context.Context, ratio float64) error {
+	m.defaultsOnce.Do(m.setDefaults)
+
+	ticker := m.clock.newTicker(m.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		used, err := m.UsedRatio()
+		if err != nil {
+			return err
+		}
+		if used < ratio {
+			return nil
+		}
+		select {
+		case <-ticker.Chan():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
-const (
-	defaultVolume        = "/"
-	defaultThreshold     = 0.9
+func (m *Maintainer) setDefaults() {
+	if m.Volume == "" {
+		m.Volume = defaultVolume
+	}
+	m.intervalChanged = make(chan struct{}, 1)
+	if m.Threshold == 0 && m.ThresholdPercent > 0 && m.ThresholdPercent < 100 {
+		m.Threshold = float64(m.ThresholdPercent) / 100
+	}
+	if m.clock == nil {
+		m.clock = realClock{}
+	}
+	if m.Threshold <= 0 || m.Threshold >= 1 {
+		m.Threshold = defaultThreshold
+	}
+	if m.CheckInterval <= 0 {
+		m.CheckInterval = defaultCheckInterval
+	}
+	if m.MinInterval <= 0 {
+		m.MinInterval = defaultMinInterval
+	}
+	if m.MinInterval > m.CheckInterval {
+		m.MinInterval = m.CheckInterval
+	}
+	if m.Jitter >= m.CheckInterval {
+		m.Jitter = m.CheckInterval / 2
+	}
+	if m.CheckRetryDelay <= 0 {
+		m.CheckRetryDelay = defaultCheckRetryDelay
+	}
+	if m.UsageProvider == nil {
+		if m.UsageFunc != nil {
+			m.UsageProvider = usageFunc(m.UsageFunc)
+		} else if project := m.QuotaProject; project != 0 {
+			m.UsageProvider = usageFunc(func(path string) (Usage, error) {
+				return ProjectQuotaUsage(path, project)
+			})
+		} else {
+			m.UsageProvider = LocalUsage{}
+		}
+	}
+	if m.PercentPrecision < 0 || (m.PercentPrecision == 0 && !m.percentPrecisionSet) {
+		m.PercentPrecision = defaultPercentPrecision
+	}
+	if m.MaxCleanAttempts <= 0 {
+		m.MaxCleanAttempts = defaultMaxCleanAttempts
+	}
+	if m.Logger == nil {
+		if m.SlogLogger != nil {
+			m.Logger = newSlogLogger(m.SlogLogger)
+		} else if m.LogFunc != nil {
+			m.Logger = newFuncLogger(m.LogFunc)
+		} else {
+			m.Logger = zap.NewNop()
+		}
+	}
+	if m.Name == "" {
+		// the volume of a device isn't known until
+		// it is resolved, so name it by the device
+		m.Name = m.Volume
+		if m.Device != "" {
+			m.Name = m.Device
+		}
+	}
+	m.Logger = m.Logger.With(zap.String("name", m.Name))
+	if m.Webhook != "" {
+		m.webhook = newWebhook(m.Webhook, m.Logger)
+	}
+}
+
+func (m *Maintainer) maintainDiskUsage(ctx context.Context) (result CheckResult, err error) {
+	// don't allow maintenance ops to overlap
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shared != nil {
+		m.shared.checkMu.Lock()
+		defer m.shared.checkMu.Unlock()
+	}
+
+	m.statsMu.Lock()
+	m.stats.LastCheck = m.clock.now()
+	m.statsMu.Unlock()
+	var du Usage
+	defer func() {
+		m.statsMu.Lock()
+		m.stats.LastError = err
+		m.stats.LastCheckCleaned = result.Cleaned
+		m.statsMu.Unlock()
+		m.writeResult(result, du, err)
+		var ce checkError
+		if m.OnCheckError != nil && errors.As(err, &ce) {
+			m.OnCheckError(err)
+		}
+	}()
+
+	du, err = m.readUsage(ctx)
+	if err != nil {
+		m.checkFailures++
+		m.emit(Event{Type: CheckFailed, Err: err})
+		return result, checkError{err}
+	}
+	m.checkFailures = 0
+
+	// readings after cleaning must be of the same
+	// volume, or what was freed can't be measured
+	m.measurePinned = true
+	defer func() { m.measurePinned = false }()
+
+	// pseudo file systems (like /proc) report no size, so
+	// ratios would be NaN and never cross any threshold
+	if du.All == 0 {
+		m.Logger.Warn("volume reports no size; skipping check",
+			zap.String("volume", m.Volume),
+			zap.String("fs_type", du.FSType),
+			zap.Uint64("total", du.All))
+		return result, nil
+	}
+
+	m.trend.add(m.stats.LastCheck, du)
+	if m.OnCheck != nil {
+		m.OnCheck(du)
+	}
+	totalMB := du.All / MB
+	usedMB := du.Used / MB
+	usedRatio := m.usedRatio(du)
+
+	// compare with the previous reading before it's replaced
+	if m.SpikeThreshold > 0 && m.lastUsage.All > 0 {
+		prevRatio := m.usedRatio(m.lastUsage)
+		if growth := usedRatio - prevRatio; growth > m.SpikeThreshold {
+			m.Logger.Warn("disk space usage spiked",
+				zap.String("volume", m.Volume),
+				zap.Float64("previous_used_ratio", prevRatio),
+				zap.Float64("used_ratio", usedRatio),
+				zap.String("used_percent", m.formatPercent(usedRatio)),
+				zap.Float64("growth", growth),
+				zap.Float64("spike_threshold", m.SpikeThreshold))
+			m.emit(Event{Type: UsageSpike, UsedRatio: usedRatio,
+				Available: du.Available, Free: du.Free})
+		}
+	}
+
+	m.recordUsage(du)
+	m.smoothUsage(usedRatio)
+	if m.MonitorOnly {
+		m.infoLogger()("disk usage",
+			zap.String("volume", m.Volume),
+			zap.String("usage", FormatBytes(du.Used)+" / "+FormatBytes(du.All)),
+			zap.String("available", FormatBytes(du.Available)),
+			zap.Float64("used_ratio", usedRatio),
+			zap.String("used_percent", m.formatPercent(usedRatio)))
+	}
+	result.UsedBefore, result.UsedAfter = du.Used, du.Used
+
+	// warn early, without cleaning, if configured
+	if m.AlertThreshold > 0 && usedRatio >= m.AlertThreshold && usedRatio < m.Threshold {
+		m.Logger.Warn("disk space usage above alert threshold",
+			zap.String("usage", FormatBytes(du.Used)+" / "+FormatBytes(du.All)),
+			zap.String("available", FormatBytes(du.Available)),
+			zap.String("free", FormatBytes(du.Free)),
+			zap.Float64("used_ratio", usedRatio),
+			zap.String("used_percent", m.formatPercent(usedRatio)),
+			zap.Float64("alert_threshold", m.AlertThreshold))
+		m.emit(Event{Type: AlertThresholdExceeded, UsedRatio: usedRatio})
+	}
+
+	// judge the cooldown once, before anything cleans,
+	// so that rules running now don't hold off Clean
+	sinceClean, coolingDown := m.cooldown()
+
+	if len(m.Rules) > 0 && !m.MonitorOnly {
+		du, err = m.applyRules(ctx, du, &result, sinceClean, coolingDown)
+		if err != nil {
+			return result, err
+		}
+		if m.Clean == nil && m.CleanBytes == nil && len(m.Cleaners) == 0 {
+			return result, nil
+		}
+		totalMB, usedMB = du.All/MB, du.Used/MB
+		usedRatio = m.usedRatio(du)
+	}
+
+	var aboveThreshold, belowMinFree, belowMinFreeRatio, aboveInodeThreshold bool
+	var triggered bool
+	if m.TriggerFunc != nil {
+		triggered = m.TriggerFunc(du)
+	} else {
+		aboveThreshold = m.triggerRatio(usedRatio) >= m.Threshold
+		belowMinFree = m.MinFree > 0 && du.Available < m.MinFree
+		belowMinFreeRatio = m.MinFreeRatio > 0 && du.All > 0 &&
+			du.availableRatio() < m.MinFreeRatio
+		aboveInodeThreshold = m.InodeThreshold > 0 && du.Inodes > 0 &&
+			du.inodeRatio() >= m.InodeThreshold
+		triggered = aboveThreshold || belowMinFree || belowMinFreeRatio || aboveInodeThreshold
+	}
+
+	// nothing to do if disk is not nearly full
+	if !triggered {
+		return result, nil
+	}
+
+	if m.SkipReadOnly && du.ReadOnly {
+		if !m.readOnlyNoticed {
+			m.Logger.Info("volume is read-only; not cleaning",
+				zap.String("volume", m.Volume),
+				zap.String("fs_type", du.FSType),
+				zap.Float64("used_ratio", usedRatio))
+			m.readOnlyNoticed = true
+		}
+		return result, nil
+	}
+
+	m.emit(Event{Type: ThresholdExceeded, UsedRatio: usedRatio})
+
+	if m.TriggerFunc != nil {
+		m.Logger.Warn("disk cleaning triggered by custom trigger",
+			zap.String("usage", FormatBytes(du.Used)+" / "+FormatBytes(du.All)),
+			zap.Float64("used_ratio", usedRatio))
+	}
+	if aboveThreshold {
+		smoothed := zap.Skip()
+		if m.Smoothing > 0 {
+			smoothed = zap.Float64("smoothed_used_ratio", m.smoothedRatio)
+		}
+		m.Logger.Warn("disk space usage above threshold",
+			zap.String("usage", FormatBytes(du.Used)+" / "+FormatBytes(du.All)),
+			zap.Uint64("total_mb", totalMB),
+			zap.Uint64("used_mb", usedMB),
+			zap.String("available", FormatBytes(du.Available)),
+			zap.String("free", FormatBytes(du.Free)),
+			zap.Float64("used_ratio", usedRatio),
+			zap.String("used_percent", m.formatPercent(usedRatio)),
+			zap.Float64("used_threshold", m.Threshold),
+			smoothed,
+			zap.String("fs_type", du.FSType),
+			zap.String("measured_volume", m.measuredVolumeName()))
+	}
+	if belowMinFree {
+		m.Logger.Warn("available disk space below minimum",
+			zap.Uint64("total_mb", totalMB),
+			zap.String("available", FormatBytes(du.Available)),
+			zap.Uint64("available_mb", du.Available/MB),
+			zap.Uint64("min_free_mb", m.MinFree/MB),
+			zap.String("measured_volume", m.measuredVolumeName()))
+	}
+	if belowMinFreeRatio {
+		m.Logger.Warn("available disk space ratio below minimum",
+			zap.String("available", FormatBytes(du.Available)+" / "+FormatBytes(du.All)),
+			zap.Float64("available_ratio", du.availableRatio()),
+			zap.Float64("min_free_ratio", m.MinFreeRatio),
+			zap.String("measured_volume", m.measuredVolumeName()))
+	}
+	if aboveInodeThreshold {
+		m.Logger.Warn("inode usage above threshold",
+			zap.Uint64("total_inodes", du.Inodes),
+			zap.Uint64("used_inodes", du.InodesUsed),
+			zap.Float64("used_inode_ratio", du.inodeRatio()),
+			zap.Float64("inode_threshold", m.InodeThreshold),
+			zap.String("measured_volume", m.measuredVolumeName()))
+	}
+
+	if m.MonitorOnly {
+		return result, nil
+	}
+
+	if m.ShouldClean != nil && !m.ShouldClean(du) {
+		m.Logger.Info("clean skipped by policy",
+			zap.String("volume", m.Volume),
+			zap.Float64("used_ratio", usedRatio))
+		return result, nil
+	}
+
+	if coolingDown {
+		m.Logger.Info("skipping clean during cooldown",
+			zap.Duration("since_last_clean", sinceClean),
+			zap.Duration("cooldown", m.CleanCooldown))
+		return result, nil
+	}
+
+	if m.DryRun {
+		m.Logger.Warn("would run clean",
+			zap.String("usage", FormatBytes(du.Used)+" / "+FormatBytes(du.All)),
+			zap.Uint64("total_mb", totalMB),
+			zap.Uint64("used_mb", usedMB),
+			zap.Uint64("available_mb", du.Available/MB),
+			zap.Float64("used_ratio", usedRatio),
+			zap.String("used_percent", m.formatPercent(usedRatio)))
+		m.emit(Event{Type: WouldClean, UsedRatio: usedRatio})
+		return result, nil
+	}
+
+	err = m.withCleanHooks(du, func() error {
+		return m.clean(ctx, du, &result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// if every cleaner was skipped, there is
+	// nothing to judge the effectiveness of
+	if !result.Cleaned {
+		return result, nil
+	}
+	minFreed := m.MinFreedBytes
+	if minFreed == 0 {
+		minFreed = 1
+	}
+	if result.Freed < minFreed {
+		return result, ErrNoSpaceFreed
+	}
+
+	return result, nil
+}
+
+// cooldown returns how long it has been since the last
+// successful clean, and whether that is within
+// m.CleanCooldown. m.mu must be held.
+func (m *Maintainer) cooldown() (time.Duration, bool) {
+	if m.CleanCooldown <= 0 || m.lastClean.IsZero() {
+		return 0, false
+	}
+	elapsed := m.clock.now().Sub(m.lastClean)
+	return elapsed, elapsed < m.CleanCooldown
+}
+
+// clean runs the cleaners in order, starting from usage du,
+// and records the outcome in result. Each cleaner runs once,
+// unless LowWaterMark is set, in which case a cleaner runs
+// again for as long as it frees space; only then does
+// cleaning escalate to the next one. Cleaning stops when
+// usage no longer needs cleaning, the cleaners are
+// exhausted, MaxCleanAttempts is reached, or usage falls
+// below MinRetainBytes or MinRetainRatio.
+func (m *Maintainer) clean(ctx context.Context, du Usage, result *CheckResult) error {
+	target := m.Threshold
+	if m.LowWaterMark > 0 {
+		target = m.LowWaterMark
+	}
+
+	cleaners := m.cleaners()
+
+	for i, attempt := 0, 1; i < len(cleaners); {
+		c := cleaners[i]
+
+		if m.belowRetainFloor(du) {
+			// usage is still above target here, so the floor
+			// is probably set too high
+			m.Logger.Warn("usage reached retain floor while still above target; not cleaning further",
+				zap.Uint64("used_mb", du.Used/MB),
+				zap.Float64("used_ratio", m.usedRatio(du)),
+				zap.Float64("target", target),
+				zap.String("min_retain_bytes", FormatBytes(m.MinRetainBytes)),
+				zap.Float64("min_retain_ratio", m.MinRetainRatio))
+			break
+		}
+
+		// cheaper cleaners may have done enough to not
+		// need this one yet
+		if c.Threshold > 0 && m.usedRatio(du) < c.Threshold {
+			i++
+			continue
+		}
+
+		// run cleaner function
+		err := m.runClean(ctx, c.cleanFunc(m.bytesNeeded(du, target)))
+		if m.shared != nil {
+			m.shared.invalidate()
+		}
+		if err != nil {
+			m.emit(Event{Type: CleanFailed, UsedRatio: m.usedRatio(du), Err: err})
+			m.statsMu.Lock()
+			m.stats.ConsecutiveCleanFailures++
+			failures := m.stats.ConsecutiveCleanFailures
+			m.statsMu.Unlock()
+			if m.MaxConsecutiveFailures > 0 &&
+				failures == m.MaxConsecutiveFailures &&
+				m.OnMaxFailures != nil {
+				m.OnMaxFailures(err)
+			}
+			return cleanError{fmt.Errorf("clean: %w", err)}
+		}
+		m.statsMu.Lock()
+		m.stats.ConsecutiveCleanFailures = 0
+		m.stats.TotalCleans++
+		m.statsMu.Unlock()
+		m.lastClean = m.clock.now()
+		result.Cleaned = true
+
+		// see how much space is now available
+		newDu, err := m.readUsage(ctx)
+		if err != nil {
+			m.emit(Event{Type: CheckFailed, Err: err})
+			return checkError{err}
+		}
+		// freed is zero, not negative, if usage grew
+		// during cleaning because of other writes
+		freed := m.recordCleaned(du, newDu, result)
+
+		logCleaned := m.infoLogger()
+		if freed == 0 {
+			logCleaned = m.Logger.Debug
+		}
+		logCleaned("disk space cleaned",
+			zap.Int("cleaner", i),
+			zap.Int("attempt", attempt),
+			zap.Uint64("used_mb", newDu.Used/MB),
+			zap.String("used_percent", m.formatPercent(m.usedRatio(newDu))),
+			zap.Uint64("freed_mb", freed/MB),
+			zap.String("freed", FormatBytes(freed)))
+
+		du = newDu
+
+		if !m.needsCleaning(du, target) || attempt >= m.MaxCleanAttempts {
+			break
+		}
+		if m.MaxFreePerCycle > 0 && result.Freed >= m.MaxFreePerCycle {
+			m.Logger.Warn("freed the most allowed per check; not cleaning further",
+				zap.String("freed", FormatBytes(result.Freed)),
+				zap.String("max_free_per_cycle", FormatBytes(m.MaxFreePerCycle)),
+				zap.Float64("used_ratio", m.usedRatio(du)))
+			break
+		}
+
+		// keep using a cleaner only if a low-water mark is
+		// set and the cleaner was effective; otherwise escalate
+		if m.LowWaterMark <= 0 || freed == 0 {
+			i++
+		}
+		attempt++
+	}
+
+	return nil
+}
+
+// withCleanHooks runs clean, which cleans starting from
+// usage du, between m.BeforeClean and m.AfterClean, if
+// they are set. If BeforeClean fails, clean does not run.
+func (m *Maintainer) withCleanHooks(du Usage, clean func() error) error {
+	if m.BeforeClean != nil {
+		if err := m.BeforeClean(du); err != nil {
+			return fmt.Errorf("clean aborted by BeforeClean: %w", err)
+		}
+	}
+	err := clean()
+	if m.AfterClean != nil {
+		m.AfterClean(du, m.lastUsage)
+	}
+	return err
+}
+
+// recordCleaned records that cleaning changed usage from
+// du to newDu, and returns the number of bytes freed.
+func (m *Maintainer) recordCleaned(du, newDu Usage, result *CheckResult) uint64 {
+	var freed uint64
+	if newDu.Used < du.Used {
+		freed = du.Used - newDu.Used
+	}
+	result.UsedAfter = newDu.Used
+	if newDu.Used < result.UsedBefore {
+		result.Freed = result.UsedBefore - newDu.Used
+	}
+
+	ineffective := m.MinEffectiveFreedBytes > 0 && freed < m.MinEffectiveFreedBytes
+
+	// update all stats of the clean at once
+	m.statsMu.Lock()
+	m.stats.TotalFreedBytes += freed
+	if ineffective {
+		m.stats.IneffectiveCleans++
+	}
+	m.recordFreed(freed)
+	m.setUsageStats(newDu)
+	m.statsMu.Unlock()
+
+	if ineffective {
+		m.Logger.Warn("clean was ineffective",
+			zap.String("freed", FormatBytes(freed)),
+			zap.String("min_effective_freed", FormatBytes(m.MinEffectiveFreedBytes)),
+			zap.Float64("used_ratio", m.usedRatio(newDu)))
+	}
+
+	if m.Metrics != nil {
+		m.Metrics.RecordUsage(m.Volume, m.Name, newDu, m.usedRatio(newDu))
+		m.Metrics.RecordClean(m.Volume, m.Name, freed)
+	}
+	m.emit(Event{Type: Cleaned, UsedRatio: m.usedRatio(newDu), Freed: freed})
+
+	// the average no longer reflects the volume
+	m.smoothedRatio = 0
+	m.smoothUsage(m.usedRatio(newDu))
+
+	return freed
+}
+
+// smoothUsage adds ratio to the moving average of the
+// used ratio, if m.Smoothing is set. m.mu must be held.
+func (m *Maintainer) smoothUsage(ratio float64) {
+	if m.Smoothing <= 0 {
+		return
+	}
+	if m.smoothedRatio == 0 {
+		m.smoothedRatio = ratio
+		return
+	}
+	m.smoothedRatio = m.Smoothing*ratio + (1-m.Smoothing)*m.smoothedRatio
+}
+
+// triggerRatio returns the used ratio to compare with
+// m.Threshold: the moving average if m.Smoothing is set,
+// or else ratio, the latest reading. m.mu must be held.
+func (m *Maintainer) triggerRatio(ratio float64) float64 {
+	if m.Smoothing > 0 && m.smoothedRatio > 0 {
+		return m.smoothedRatio
+	}
+	return ratio
+}
+
+// needsCleaning returns true if du is at or above the
+// ratio threshold, or below the configured minimum free
+// space, or at or above the inode threshold, unless
+// m.TriggerFunc is set, in which case it decides.
+func (m *Maintainer) needsCleaning(du Usage, threshold float64) bool {
+	if m.TriggerFunc != nil {
+		return m.TriggerFunc(du)
+	}
+	return m.usedRatio(du) >= threshold ||
+		(m.MinFree > 0 && du.Available < m.MinFree) ||
+		(m.MinFreeRatio > 0 && du.All > 0 && du.availableRatio() < m.MinFreeRatio) ||
+		(m.InodeThreshold > 0 && du.Inodes > 0 && du.inodeRatio() >= m.InodeThreshold)
+}
+
+// belowRetainFloor returns true if the used space of du
+// is below m.MinRetainBytes or m.MinRetainRatio.
+func (m *Maintainer) belowRetainFloor(du Usage) bool {
+	return (m.MinRetainBytes > 0 && du.Used < m.MinRetainBytes) ||
+		(m.MinRetainRatio > 0 && m.usedRatio(du) < m.MinRetainRatio)
+}
+
+// canRun returns true if m is either monitoring only or
+// has something to clean with.
+func (m *Maintainer) canRun() bool {
+	return m.MonitorOnly || m.hasCleaner()
+}
+
+// hasCleaner returns true if m has anything to clean with.
+func (m *Maintainer) hasCleaner() bool {
+	for _, c := range m.Cleaners {
+		if c.Clean == nil && c.CleanBytes == nil {
+			return false
+		}
+	}
+	for _, r := range m.Rules {
+		if r.Clean == nil {
+			return false
+		}
+	}
+	return m.Clean != nil || m.CleanBytes != nil || len(m.Cleaners) > 0 || len(m.Rules) > 0
+}
+
+// cleaners returns the cleaners to run, in order.
+func (m *Maintainer) cleaners() []Cleaner {
+	if len(m.Cleaners) > 0 {
+		return m.Cleaners
+	}
+	return []Cleaner{{Clean: m.Clean, CleanBytes: m.CleanBytes}}
+}
+
+// bytesNeeded returns how many bytes must be freed from
+// du for usage to drop below the ratio threshold and for
+// available space to reach m.MinFree and m.MinFreeRatio.
+func (m *Maintainer) bytesNeeded(du Usage, threshold float64) uint64 {
+	used := du.All - du.Available
+	if m.UsageBasis == BasisFree && !m.UseAvailable {
+		used = du.Used
+	}
+
+	var needed uint64
+	if limit := uint64(threshold * float64(du.All)); used > limit {
+		needed = used - limit
+	}
+	if m.MinFree > du.Available && m.MinFree-du.Available > needed {
+		needed = m.MinFree - du.Available
+	}
+	if m.MinFreeRatio > 0 {
+		minFree := uint64(m.MinFreeRatio * float64(du.All))
+		if minFree > du.Available && minFree-du.Available > needed {
+			needed = minFree - du.Available
+		}
+	}
+	return needed
+}
+
+// readUsage reads the disk usage of m.Volume, retrying
+// up to m.CheckRetries times if it fails.
+func (m *Maintainer) readUsage(ctx context.Context) (Usage, error) {
+	du, err := m.readUsageOnce(ctx)
+	for i := 0; err != nil && i < m.CheckRetries; i++ {
+		m.Logger.Debug("retrying disk usage read",
+			zap.Int("retry", i+1),
+			zap.Error(err))
+		timer := m.clock.newTimer(m.CheckRetryDelay)
+		select {
+		case <-timer.Chan():
+		case <-ctx.Done():
+			timer.Stop()
+			return du, err
+		}
+		du, err = m.readUsageOnce(ctx)
+	}
+	return du, err
+}
+
+// readUsageOnce reads the disk usage of m.Volume, sharing
+// the reading with other maintainers of the same file
+// system when managed together. With MeasureVolumes, it
+// picks the volume that decides, except after the first
+// reading of a check, when it reads the same volume again.
+func (m *Maintainer) readUsageOnce(ctx context.Context) (Usage, error) {
+	if m.shared != nil {
+		return m.shared.read(func() (Usage, error) {
+			return m.statUsage(ctx, m.usagePath())
+		})
+	}
+	if len(m.MeasureVolumes) == 0 {
+		return m.statUsage(ctx, m.usagePath())
+	}
+	if m.measurePinned {
+		return m.statUsage(ctx, m.measuredVolume)
+	}
+
+	usages, err := m.readVolumes(ctx)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	// the first volume that needs cleaning decides;
+	// otherwise, the fullest one
+	pick := 0
+	for i, du := range usages {
+		if m.needsCleaning(du, m.Threshold) {
+			pick = i
+			break
+		}
+		if m.usedRatio(du) > m.usedRatio(usages[pick]) {
+			pick = i
+		}
+	}
+	m.measuredVolume = m.MeasureVolumes[pick]
+	return usages[pick], nil
+}
+
+// measuredVolumeName returns the volume whose usage was
+// read last. m.mu must be held.
+func (m *Maintainer) measuredVolumeName() string {
+	if len(m.MeasureVolumes) == 0 {
+		return m.Volume
+	}
+	return m.measuredVolume
+}
+
+// readVolumes reads the disk usage of each of
+// m.MeasureVolumes, or of m.Volume if there are none.
+func (m *Maintainer) readVolumes(ctx context.Context) ([]Usage, error) {
+	if len(m.MeasureVolumes) == 0 {
+		du, err := m.statUsage(ctx, m.usagePath())
+		if err != nil {
+			return nil, err
+		}
+		return []Usage{du}, nil
+	}
+	usages := make([]Usage, 0, len(m.MeasureVolumes))
+	for _, vol := range m.MeasureVolumes {
+		du, err := m.statUsage(ctx, vol)
+		if err != nil {
+			return nil, fmt.Errorf("measuring %s: %w", vol, err)
+		}
+		usages = append(usages, du)
+	}
+	return usages, nil
+}
+
+// statUsage reads the disk usage of path with
+// m.UsageProvider, bounded by m.StatfsTimeout if set.
+func (m *Maintainer) statUsage(ctx context.Context, path string) (Usage, error) {
+	if m.StatfsTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.StatfsTimeout)
+		defer cancel()
+	}
+	return m.UsageProvider.Usage(ctx, path)
+}
+
+// usageContext calls usageFunc for path, but returns early
+// if ctx is done first. The call is left to finish on its
+// own, since a blocked statfs cannot be interrupted.
+func usageContext(ctx context.Context, path string, usageFunc func(string) (Usage, error)) (Usage, error) {
+	type reading struct {
+		du  Usage
+		err error
+	}
+	done := make(chan reading, 1)
+	go func() {
+		du, err := usageFunc(path)
+		done <- reading{du, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.du, r.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Usage{}, fmt.Errorf("reading disk usage of %s timed out; the file system may be unresponsive: %w", path, ctx.Err())
+		}
+		return Usage{}, ctx.Err()
+	}
+}
+
+// usedRatio returns the ratio of used/total space
+// for du according to m's configuration.
+func (m *Maintainer) usedRatio(du Usage) float64 {
+	if m.UsageBasis == BasisFree && !m.UseAvailable {
+		return du.usedRatio()
+	}
+	return du.unavailableRatio()
+}
+
+// recordUsage records a reading of disk usage. m.mu
+// must be held.
+func (m *Maintainer) recordUsage(du Usage) {
+	m.statsMu.Lock()
+	m.setUsageStats(du)
+	m.statsMu.Unlock()
+	if m.Metrics != nil {
+		m.Metrics.RecordUsage(m.Volume, m.Name, du, m.usedRatio(du))
+	}
+}
+
+// setUsageStats updates m's stats with the reading du.
+// m.mu and m.statsMu must be held.
+func (m *Maintainer) setUsageStats(du Usage) {
+	m.lastUsage = du
+	m.stats.LastUsedRatio = m.usedRatio(du)
+	m.stats.FSType = du.FSType
+	m.stats.LastAvailable = du.Available
+	m.stats.LastFree = du.Free
+}
+
+// runClean calls clean, bounded by m.CleanTimeout
+// if set. On timeout, it returns without waiting for
+// clean to finish so that the lock can be released.
+func (m *Maintainer) runClean(ctx context.Context, clean func(context.Context) error) error {
+	if m.CleanTimeout <= 0 {
+		return m.safeClean(ctx, clean)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.CleanTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	m.inflight.Add(1)
+	go func() {
+		defer m.inflight.Done()
+		done <- m.safeClean(ctx, clean)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s: %w", m.CleanTimeout, ctx.Err())
+		}
+		return ctx.Err()
+	}
+}
+
+// safeClean calls clean, converting a panic
+// into an error so that maintenance can go on.
+func (m *Maintainer) safeClean(ctx context.Context, clean func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.Logger.Error("clean function panicked",
+				zap.Any("panic", r),
+				zap.ByteString("stack", debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return clean(ctx)
+}
+
+// UsageBasis determines which space counts as used when
+// computing the ratio of used/total space on a volume.
+// The two differ on file systems that reserve space for
+// privileged users, such as ext4.
+type UsageBasis int
+
+const (
+	// BasisAvailable counts any space that is not
+	// available to unprivileged users as used, including
+	// reserved space: 1 - available/total. This matches
+	// what df reports to normal users.
+	BasisAvailable UsageBasis = iota
+
+	// BasisFree counts only space actually occupied as
+	// used, treating reserved space as unused:
+	// (total - free)/total.
+	BasisFree
+)
+
+// Usage describes the space utilization of a volume.
+// All values are in bytes.
+type Usage struct {
+	// Total size of the volume.
+	All uint64 `json:"all"`
+
+	// Space available to unprivileged users.
+	Available uint64 `json:"available"`
+
+	// Free space, including any space
+	// reserved for privileged users.
+	Free uint64 `json:"free"`
+
+	// Space in use.
+	Used uint64 `json:"used"`
+
+	// Total number of inodes on the volume.
+	// Zero if the volume does not report them.
+	Inodes uint64 `json:"inodes"`
+
+	// Number of free inodes.
+	InodesFree uint64 `json:"inodes_free"`
+
+	// Number of inodes in use.
+	InodesUsed uint64 `json:"inodes_used"`
+
+	// Whether the volume is mounted read-only.
+	ReadOnly bool `json:"read_only"`
+
+	// The type of file system (e.g. "ext4" or
+	// "tmpfs"), if known. On Linux, ext2, ext3
+	// and ext4 cannot be told apart, and unknown
+	// types are given by their magic number in hex.
+	FSType string `json:"fs_type,omitempty"`
+}
+
+// usedRatio returns the ratio of used/total space. It is
+// computed from bytes, not rounded to MB, so that it is
+// exact even for small volumes.
+func (u Usage) usedRatio() float64 {
+	return float64(u.Used) / float64(u.All)
+}
+
+// unavailableRatio returns the ratio of space that is
+// not available to unprivileged users, including
+// space reserved for privileged users.
+func (u Usage) unavailableRatio() float64 {
+	return 1 - float64(u.Available)/float64(u.All)
+}
+
+// availableRatio returns the ratio of available/total
+// space.
+func (u Usage) availableRatio() float64 {
+	return float64(u.Available) / float64(u.All)
+}
+
+// inodeRatio returns the ratio of used/total inodes.
+func (u Usage) inodeRatio() float64 {
+	return float64(u.InodesUsed) / float64(u.Inodes)
+}
+
+// DiskUsageContext is like DiskUsage, but gives up when
+// ctx is done. Reading the disk usage of an unresponsive
+// file system cannot be interrupted, so in that case the
+// reading is abandoned and finishes in the background.
+func DiskUsageContext(ctx context.Context, path string) (Usage, error) {
+	return usageContext(ctx, path, DiskUsage)
+}
+
+// DiskUsage returns the disk usage of the volume
+// containing path.
+func DiskUsage(path string) (Usage, error) {
+	du, err := diskUsage(path)
+	if err != nil {
+		return Usage{}, err
+	}
+	return du.usage(), nil
+}
+
+type diskStatus struct {
+	all, available, free, used  uint64
+	files, filesFree, filesUsed uint64
+	readOnly                    bool
+	fsType                      string
+}
+
+// nonNegative converts n to unsigned,
+// treating negative values as zero.
+func nonNegative(n int64) uint64 {
+	if n < 0 {
+		return 0
+	}
+	return uint64(n)
+}
+
+func (ds diskStatus) usage() Usage {
+	return Usage{
+		All:        ds.all,
+		Available:  ds.available,
+		Free:       ds.free,
+		Used:       ds.used,
+		Inodes:     ds.files,
+		InodesFree: ds.filesFree,
+		InodesUsed: ds.filesUsed,
+		ReadOnly:   ds.readOnly,
+		FSType:     ds.fsType,
+	}
+}
+
+// checkError wraps a failure to read disk usage during
+// a check. It matches ErrCheckFailed.
+type checkError struct{ err error }
+
+func (e checkError) Error() string        { return e.err.Error() }
+func (e checkError) Unwrap() error        { return e.err }
+func (e checkError) Is(target error) bool { return target == ErrCheckFailed }
+
+// cleanError wraps a failure of Clean. It matches
+// ErrCleanFailed.
+type cleanError struct{ err error }
+
+func (e cleanError) Error() string        { return e.err.Error() }
+func (e cleanError) Unwrap() error        { return e.err }
+func (e cleanError) Is(target error) bool { return target == ErrCleanFailed }
+
+// volumeError wraps a failure to find or read the volume
+// when maintenance starts. It matches ErrVolumeNotFound.
+type volumeError struct {
+	volume string
+	err    error
+}
+
+func (e volumeError) Error() string {
+	return fmt.Sprintf("volume %s does not exist or is not accessible: %v", e.volume, e.err)
+}
+func (e volumeError) Unwrap() error        { return e.err }
+func (e volumeError) Is(target error) bool { return target == ErrVolumeNotFound }
+
+// ErrVolumeNotFound matches errors returned when the
+// volume to maintain does not exist or cannot be read
+// when maintenance starts.
+var ErrVolumeNotFound = errors.New("volume not found")
+
+// ErrCheckFailed matches errors returned when disk
+// usage cannot be read during a check. The underlying
+// error is wrapped, so errors.Is and errors.As can also
+// find it.
+var ErrCheckFailed = errors.New("check failed")
+
+// ErrCleanFailed matches errors returned when Clean (or
+// a cleaner) fails. The error from Clean is wrapped, so
+// errors.Is and errors.As can also find it.
+var ErrCleanFailed = errors.New("clean failed")
+
+// ErrNoSpaceFreed is returned when disk cleaning
+// did not free enough space to be effective.
+var ErrNoSpaceFreed = errors.New("clean did not free enough space")
+
+// errNoSize is returned when a volume reports no size,
+// as pseudo file systems do, so it has no used ratio.
+var errNoSize = errors.New("volume reports no size")
+
+// ErrTooSoon is returned by CheckNow when it is called
+// within MinCheckInterval of the previous check.
+var ErrTooSoon = errors.New("too soon since last check")
+
+const (
+	defaultVolume        = "/"
+	defaultThreshold     = 0.9
 	defaultCheckInterval = 10 * time.Minute
+	defaultMinInterval   = time.Minute
+
+	defaultCheckRetryDelay = time.Second
+
+	defaultPercentPrecision = 1
+	defaultMaxCleanAttempts = 10
 )
 
-// Disk size constants.
+// Disk size constants. These are binary units, so KB is
+// 1024 bytes (a KiB), MB is 1024 KB (a MiB), and so on.
 const (
 	_  = iota
 	KB = 1 << (10 * iota)
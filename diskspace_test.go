@@ -0,0 +1,221 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZeroSizeVolume(t *testing.T) {
+	var cleans int
+	m := &Maintainer{
+		Threshold: 0.5,
+		UsageFunc: func(string) (Usage, error) { return Usage{}, nil },
+		Clean:     func(context.Context) error { cleans++; return nil },
+	}
+
+	result, err := m.CheckNow()
+	if err != nil {
+		t.Fatalf("CheckNow: expected no error, got %v", err)
+	}
+	if result.Cleaned || cleans != 0 {
+		t.Errorf("expected no clean, but Clean ran %d times", cleans)
+	}
+
+	if ratio, err := m.UsedRatio(); !errors.Is(err, errNoSize) {
+		t.Errorf("UsedRatio: expected errNoSize, got %v (ratio %v)", err, ratio)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("ServeHTTP: expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestResultOfZeroSizeVolume(t *testing.T) {
+	var out bytes.Buffer
+	du := Usage{}
+	m := &Maintainer{
+		ResultWriter: &out,
+		UsageFunc:    func(string) (Usage, error) { return du, nil },
+		Clean:        func(context.Context) error { return nil },
+	}
+
+	// a volume with no size, then with a size, then with
+	// none again, whose reading must not be the previous one
+	for _, all := range []uint64{0, 100, 0} {
+		du = Usage{All: all, Used: all / 10, Available: all - all/10}
+		if _, err := m.CheckNow(); err != nil {
+			t.Fatalf("CheckNow: %v", err)
+		}
+	}
+
+	var records []checkRecord
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var rec checkRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("decoding %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(records))
+	}
+	for i, rec := range records {
+		hasUsage := i == 1
+		if (rec.Usage != nil) != hasUsage {
+			t.Errorf("result %d: expected usage: %v, got %+v", i, hasUsage, rec.Usage)
+		}
+	}
+}
+
+func TestUsageGrewDuringClean(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	used := uint64(60 * MB)
+	m := &Maintainer{
+		Threshold: 0.5,
+		Logger:    zap.New(core),
+		UsageFunc: func(string) (Usage, error) {
+			du := Usage{All: 100 * MB, Used: used}
+			used += 10 * MB // other processes keep writing
+			return du, nil
+		},
+		Clean: func(context.Context) error { return nil },
+	}
+
+	result, err := m.CheckNow()
+	if !errors.Is(err, ErrNoSpaceFreed) {
+		t.Errorf("expected ErrNoSpaceFreed, got %v", err)
+	}
+	if !result.Cleaned {
+		t.Fatal("expected a clean")
+	}
+	if result.Freed != 0 {
+		t.Errorf("expected 0 bytes freed, got %d", result.Freed)
+	}
+	if result.UsedAfter != 70*MB {
+		t.Errorf("expected %d bytes used after cleaning, got %d", 70*MB, result.UsedAfter)
+	}
+
+	cleaned := logs.FilterMessage("disk space cleaned").All()
+	if len(cleaned) != 1 {
+		t.Fatalf("expected 1 log of cleaning, got %d", len(cleaned))
+	}
+	if freedMB := cleaned[0].ContextMap()["freed_mb"]; freedMB != uint64(0) {
+		t.Errorf("expected freed_mb of 0, got %v", freedMB)
+	}
+}
+
+func TestUsedRatioOfSmallVolume(t *testing.T) {
+	// less than 2 MiB, so ratios of whole MiB would be 0/1
+	du := Usage{All: 3 * MB / 2, Used: 9 * MB / 10}
+	du.Available = du.All - du.Used
+	du.Free = du.Available
+
+	for _, basis := range []UsageBasis{BasisFree, BasisAvailable} {
+		m := &Maintainer{UsageBasis: basis}
+		if ratio := m.usedRatio(du); math.Abs(ratio-0.6) > 1e-6 {
+			t.Errorf("basis %v: expected ratio of 0.6, got %v", basis, ratio)
+		}
+	}
+
+	var cleans int
+	m := &Maintainer{
+		Threshold: 0.5,
+		UsageFunc: func(string) (Usage, error) { return du, nil },
+		Clean: func(context.Context) error {
+			cleans++
+			du.Used, du.Available, du.Free = 0, du.All, du.All
+			return nil
+		},
+	}
+	if _, err := m.CheckNow(); err != nil {
+		t.Fatalf("CheckNow: %v", err)
+	}
+	if cleans != 1 {
+		t.Errorf("expected 1 clean at a ratio of 0.6, got %d", cleans)
+	}
+}
+
+func TestStartAfterMaintainReturns(t *testing.T) {
+	m := &Maintainer{
+		MaxDuration: 10 * time.Millisecond,
+		UsageFunc:   func(string) (Usage, error) { return Usage{All: 100, Used: 10, Available: 90}, nil },
+		Clean:       func(context.Context) error { return nil },
+	}
+
+	for i := 0; i < 2; i++ {
+		m.Start()
+		if !m.IsRunning() {
+			t.Fatalf("start %d: expected to be running", i)
+		}
+		deadline := time.Now().Add(time.Second)
+		for m.IsRunning() && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if m.IsRunning() {
+			t.Fatalf("start %d: expected to stop running after MaxDuration", i)
+		}
+	}
+	m.Stop()
+}
+
+func TestUsedRatioWhileRunning(t *testing.T) {
+	m := &Maintainer{
+		ContainerAware: true,
+		UsageFunc:      func(string) (Usage, error) { return Usage{All: 100, Used: 10, Available: 90}, nil },
+		Clean:          func(context.Context) error { return nil },
+	}
+	m.Start()
+	defer m.Stop()
+	for i := 0; i < 100; i++ {
+		if _, err := m.UsedRatio(); err != nil {
+			t.Fatalf("UsedRatio: %v", err)
+		}
+	}
+}
+
+func TestTimeoutsMatchDeadlineExceeded(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	m := &Maintainer{
+		StatfsTimeout: time.Millisecond,
+		UsageFunc: func(string) (Usage, error) {
+			<-block
+			return Usage{}, nil
+		},
+		Clean: func(context.Context) error { return nil },
+	}
+	if _, err := m.CheckNow(); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrCheckFailed) {
+		t.Errorf("statfs timeout: expected context.DeadlineExceeded and ErrCheckFailed, got %v", err)
+	}
+
+	m = &Maintainer{
+		Threshold:    0.5,
+		CleanTimeout: time.Millisecond,
+		UsageFunc:    func(string) (Usage, error) { return Usage{All: 100, Used: 90, Available: 10}, nil },
+		Clean: func(context.Context) error {
+			<-block
+			return nil
+		},
+	}
+	if _, err := m.CheckNow(); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrCleanFailed) {
+		t.Errorf("clean timeout: expected context.DeadlineExceeded and ErrCleanFailed, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import "time"
+
+// EventType identifies the kind of an Event.
+type EventType int
+
+// Event types.
+const (
+	// Disk usage crossed a threshold that
+	// triggers cleaning.
+	ThresholdExceeded EventType = iota + 1
+
+	// Clean ran successfully.
+	Cleaned
+
+	// Clean returned an error.
+	CleanFailed
+
+	// Disk usage could not be read.
+	CheckFailed
+
+	// Disk usage crossed the alert threshold
+	// but not the clean threshold.
+	AlertThresholdExceeded
+
+	// Clean would have run, but DryRun is set.
+	WouldClean
+
+	// Disk usage grew by more than SpikeThreshold
+	// since the previous check.
+	UsageSpike
+)
+
+func (t EventType) String() string {
+	switch t {
+	case ThresholdExceeded:
+		return "threshold_exceeded"
+	case Cleaned:
+		return "cleaned"
+	case CleanFailed:
+		return "clean_failed"
+	case CheckFailed:
+		return "check_failed"
+	case AlertThresholdExceeded:
+		return "alert_threshold_exceeded"
+	case WouldClean:
+		return "would_clean"
+	case UsageSpike:
+		return "usage_spike"
+	}
+	return "unknown"
+}
+
+// Event describes something notable that happened
+// during maintenance.
+type Event struct {
+	Type   EventType
+	Time   time.Time
+	Volume string
+
+	// The ratio of used/total space at the
+	// time of the event, if known.
+	UsedRatio float64
+
+	// Bytes freed by Clean, for Cleaned events.
+	Freed uint64
+
+	// Space available to unprivileged users, and
+	// free space including any reserved space, as
+	// of the last successful reading.
+	Available, Free uint64
+
+	// The error, for CleanFailed and
+	// CheckFailed events.
+	Err error
+}
+
+// emit sends an event on m.Events, and to m.Webhook if
+// it is of interest, without blocking. If the channel is
+// not ready, the event is dropped.
+// m.mu must be held.
+func (m *Maintainer) emit(event Event) {
+	if m.Events == nil && m.webhook == nil {
+		return
+	}
+	event.Time = m.clock.now()
+	event.Volume = m.Volume
+	if event.Available == 0 && event.Free == 0 {
+		event.Available = m.lastUsage.Available
+		event.Free = m.lastUsage.Free
+	}
+	m.notifyWebhook(event)
+	if m.Events == nil {
+		return
+	}
+	select {
+	case m.Events <- event:
+	default:
+	}
+}
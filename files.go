@@ -0,0 +1,365 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DeleteOption configures the file deletion helpers.
+type DeleteOption func(*deleteConfig) error
+
+type deleteConfig struct {
+	audit   func(path string, size uint64)
+	exclude []string
+	protect func(path string) bool
+}
+
+// WithAuditFunc calls audit with the path and size of
+// each file after it is deleted, for keeping a record of
+// what was removed.
+func WithAuditFunc(audit func(path string, size uint64)) DeleteOption {
+	return func(c *deleteConfig) error {
+		c.audit = audit
+		return nil
+	}
+}
+
+// WithExclude prevents files that match any of the
+// patterns from being deleted, using filepath.Match
+// syntax. A pattern matches either a file's name or its
+// path relative to the directory being cleaned, so
+// ".keep" and "locks/*.lock" both work. Excluded files
+// do not count toward the space to free.
+func WithExclude(patterns ...string) DeleteOption {
+	return func(c *deleteConfig) error {
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("exclude pattern %q: %w", pattern, err)
+			}
+		}
+		c.exclude = append(c.exclude, patterns...)
+		return nil
+	}
+}
+
+// WithProtect prevents files for which protect returns
+// true from being deleted. It is called with the path of
+// each candidate file.
+func WithProtect(protect func(path string) bool) DeleteOption {
+	return func(c *deleteConfig) error {
+		c.protect = protect
+		return nil
+	}
+}
+
+func newDeleteConfig(opts []DeleteOption) (deleteConfig, error) {
+	var c deleteConfig
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// excluded returns true if f, within dir, must not be
+// deleted.
+func (c deleteConfig) excluded(dir string, f fileEntry) bool {
+	if c.protect != nil && c.protect(f.path) {
+		return true
+	}
+	if len(c.exclude) == 0 {
+		return false
+	}
+	name := filepath.Base(f.path)
+	rel, err := filepath.Rel(dir, f.path)
+	if err != nil {
+		rel = name
+	}
+	for _, pattern := range c.exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// remove deletes f and reports it to the audit func, if any.
+func (c deleteConfig) remove(f fileEntry) error {
+	if err := os.Remove(f.path); err != nil {
+		return err
+	}
+	if c.audit != nil {
+		c.audit(f.path, f.size)
+	}
+	return nil
+}
+
+// DeleteOldestFiles deletes regular files in dir and its
+// subdirectories, oldest modification time first, until
+// the volume containing dir has at least targetFree bytes
+// available. It returns the number of bytes deleted. It
+// is suitable for use in a Clean function.
+func DeleteOldestFiles(dir string, targetFree uint64, opts ...DeleteOption) (freed uint64, err error) {
+	return deleteOldest(dir, targetFree, nil, opts)
+}
+
+// DeleteMatching is like DeleteOldestFiles, but only
+// deletes files whose names match at least one of the
+// patterns, using filepath.Match syntax (e.g. "*.tmp").
+func DeleteMatching(dir string, patterns []string, targetFree uint64, opts ...DeleteOption) (freed uint64, err error) {
+	// validate patterns up front, so a bad one
+	// isn't mistaken for one that matches nothing
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return 0, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+	}
+	return deleteOldest(dir, targetFree, func(f fileEntry) bool {
+		name := filepath.Base(f.path)
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}, opts)
+}
+
+// deleteOldest deletes files in dir for which include
+// returns true (or all files, if include is nil), oldest
+// first, until the volume has targetFree bytes available.
+func deleteOldest(dir string, targetFree uint64, include func(fileEntry) bool, opts []DeleteOption) (freed uint64, err error) {
+	cfg, err := newDeleteConfig(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	du, err := DiskUsage(dir)
+	if err != nil {
+		return 0, err
+	}
+	if du.Available >= targetFree {
+		return 0, nil
+	}
+
+	files, err := regularFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	needed := targetFree - du.Available
+	for _, f := range files {
+		if include != nil && !include(f) {
+			continue
+		}
+		if cfg.excluded(dir, f) {
+			continue
+		}
+		if err := cfg.remove(f); err != nil {
+			return freed, err
+		}
+		freed += f.size
+
+		// file sizes are only an estimate of the space
+		// they occupy, so confirm with the volume itself
+		if freed >= needed {
+			du, err = DiskUsage(dir)
+			if err != nil {
+				return freed, err
+			}
+			if du.Available >= targetFree {
+				return freed, nil
+			}
+			needed = freed + (targetFree - du.Available)
+		}
+	}
+
+	return freed, nil
+}
+
+// DeleteFilesOlderThan deletes regular files in dir and
+// its subdirectories that were last modified longer than
+// age ago. Symbolic links are neither followed nor deleted.
+// Failure to delete a file does not stop the others from
+// being deleted; all such errors are returned together.
+// It returns the number of bytes and files deleted.
+func DeleteFilesOlderThan(dir string, age time.Duration, opts ...DeleteOption) (freed uint64, deleted int, err error) {
+	cfg, err := newDeleteConfig(opts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	files, err := regularFiles(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cutoff := time.Now().Add(-age)
+
+	var errs []error
+	for _, f := range files {
+		if !f.modTime.Before(cutoff) || cfg.excluded(dir, f) {
+			continue
+		}
+		if err := cfg.remove(f); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		freed += f.size
+		deleted++
+	}
+
+	return freed, deleted, errors.Join(errs...)
+}
+
+// DirSize returns the total size of the regular files in
+// dir and its subdirectories. Symbolic links are not
+// followed, so nothing is counted twice and links cannot
+// form cycles.
+func DirSize(dir string) (uint64, error) {
+	return dirSize(dir, func(info os.FileInfo) uint64 {
+		return uint64(info.Size())
+	})
+}
+
+// DirSizeOnDisk is like DirSize, but counts the space
+// allocated to each file rather than its length, which is
+// more accurate for sparse files. On platforms that do
+// not report allocated space, it is the same as DirSize.
+func DirSizeOnDisk(dir string) (uint64, error) {
+	return dirSize(dir, allocatedSize)
+}
+
+func dirSize(dir string, size func(os.FileInfo) uint64) (uint64, error) {
+	var total uint64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += size(info)
+		}
+		return nil
+	})
+	return total, err
+}
+
+// Entry is a file or directory found by LargestEntries.
+type Entry struct {
+	Path    string
+	Size    uint64 // total size of its files, for a directory
+	ModTime time.Time
+	IsDir   bool
+}
+
+// LargestEntries returns the n largest regular files and
+// directories directly within dir, largest first, or all
+// of them if n <= 0. The size of a directory is the total
+// size of the files within it, as with DirSize. Entries,
+// or parts of directories, that cannot be read (usually
+// for lack of permission) are skipped, and the errors
+// are returned together along with the entries that
+// could be measured.
+func LargestEntries(dir string, n int) ([]Entry, error) {
+	children, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	var errs []error
+	for _, child := range children {
+		path := filepath.Join(dir, child.Name())
+		info, err := child.Info()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch {
+		case info.Mode().IsRegular():
+			entries = append(entries, Entry{
+				Path:    path,
+				Size:    uint64(info.Size()),
+				ModTime: info.ModTime(),
+			})
+		case info.IsDir():
+			size, walkErrs := readableDirSize(path)
+			errs = append(errs, walkErrs...)
+			entries = append(entries, Entry{
+				Path:    path,
+				Size:    size,
+				ModTime: info.ModTime(),
+				IsDir:   true,
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries, errors.Join(errs...)
+}
+
+// readableDirSize is like DirSize, but skips what it
+// cannot read instead of stopping, and returns the errors.
+func readableDirSize(dir string) (uint64, []error) {
+	var total uint64
+	var errs []error
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			total += uint64(info.Size())
+		}
+		return nil
+	})
+	return total, errs
+}
+
+type fileEntry struct {
+	path    string
+	size    uint64
+	modTime time.Time
+}
+
+// regularFiles lists the regular files within dir,
+// recursively. Symbolic links are not followed.
+func regularFiles(dir string) ([]fileEntry, error) {
+	var files []fileEntry
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		files = append(files, fileEntry{
+			path:    path,
+			size:    uint64(info.Size()),
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeFiles creates files in a new temporary directory,
+// keyed by slash-separated path relative to it with
+// their sizes as values, all last modified an hour ago.
+func makeFiles(t *testing.T, files map[string]int) string {
+	t.Helper()
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for name, size := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDeleteExcluded(t *testing.T) {
+	files := map[string]int{
+		"a.log":          100,
+		".keep":          10,
+		"sub/.keep":      20,
+		"sub/b.log":      200,
+		"locks/app.lock": 30,
+		"locks/c.log":    300,
+		"protected.log":  40,
+	}
+	kept := []string{".keep", "sub/.keep", "locks/app.lock", "protected.log"}
+
+	opts := func(dir string) []DeleteOption {
+		return []DeleteOption{
+			WithExclude(".keep", "locks/*.lock"),
+			WithProtect(func(path string) bool {
+				return path == filepath.Join(dir, "protected.log")
+			}),
+		}
+	}
+	check := func(t *testing.T, dir string, freed, wantFreed uint64) {
+		t.Helper()
+		if freed != wantFreed {
+			t.Errorf("expected %d bytes freed, got %d", wantFreed, freed)
+		}
+		for _, name := range kept {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				t.Errorf("expected %s to be kept: %v", name, err)
+			}
+		}
+	}
+
+	t.Run("DeleteOldestFiles", func(t *testing.T) {
+		dir := makeFiles(t, files)
+		freed, err := DeleteOldestFiles(dir, math.MaxUint64, opts(dir)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, dir, freed, 600)
+	})
+
+	t.Run("DeleteMatching", func(t *testing.T) {
+		dir := makeFiles(t, files)
+		freed, err := DeleteMatching(dir, []string{"*.log", "*.lock"}, math.MaxUint64, opts(dir)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, dir, freed, 600)
+	})
+
+	t.Run("DeleteFilesOlderThan", func(t *testing.T) {
+		dir := makeFiles(t, files)
+		freed, deleted, err := DeleteFilesOlderThan(dir, time.Minute, opts(dir)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted != 3 {
+			t.Errorf("expected 3 files deleted, got %d", deleted)
+		}
+		check(t, dir, freed, 600)
+	})
+}
@@ -0,0 +1,15 @@
+// Copyright 2020 Matthew Holt
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package diskspace
+
+import "path/filepath"
+
+// fileSystemID returns an identifier of the file system
+// containing path. On this platform, there is no way to
+// tell, so each distinct path is its own file system.
+func fileSystemID(path string) (string, error) {
+	return filepath.Abs(path)
+}
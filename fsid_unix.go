@@ -0,0 +1,23 @@
+// Copyright 2020 Matthew Holt
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package diskspace
+
+import (
+	"strconv"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// fileSystemID returns an identifier of the file system
+// containing path, which is the same for all paths on
+// the same file system.
+func fileSystemID(path string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(uint64(st.Dev), 10), nil
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// fileSystemID returns an identifier of the file system
+// containing path, which is the same for all paths on
+// the same file system.
+func fileSystemID(path string) (string, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]uint16, windows.MAX_PATH+1)
+	err = windows.GetVolumePathName(pathPtr, &buf[0], uint32(len(buf)))
+	if err != nil {
+		return "", err
+	}
+	return windows.UTF16ToString(buf), nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"fmt"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// zfsSuperMagic is the magic number of ZFS, which is
+// not part of the kernel.
+const zfsSuperMagic = 0x2fc12fc1
+
+// fsTypeNames maps the magic numbers reported by statfs(2)
+// to the names of common file system types.
+var fsTypeNames = map[uint32]string{
+	syscall.BTRFS_SUPER_MAGIC:     "btrfs",
+	syscall.CEPH_SUPER_MAGIC:      "ceph",
+	syscall.CGROUP2_SUPER_MAGIC:   "cgroup2",
+	syscall.CIFS_SUPER_MAGIC:      "cifs",
+	syscall.EXFAT_SUPER_MAGIC:     "exfat",
+	syscall.EXT4_SUPER_MAGIC:      "ext2/ext3/ext4",
+	syscall.F2FS_SUPER_MAGIC:      "f2fs",
+	syscall.FUSE_SUPER_MAGIC:      "fuse",
+	syscall.HUGETLBFS_MAGIC:       "hugetlbfs",
+	syscall.ISOFS_SUPER_MAGIC:     "iso9660",
+	syscall.MSDOS_SUPER_MAGIC:     "vfat",
+	syscall.NFS_SUPER_MAGIC:       "nfs",
+	syscall.OVERLAYFS_SUPER_MAGIC: "overlay",
+	syscall.PROC_SUPER_MAGIC:      "proc",
+	syscall.RAMFS_MAGIC:           "ramfs",
+	syscall.REISERFS_SUPER_MAGIC:  "reiserfs",
+	syscall.SMB2_SUPER_MAGIC:      "smb2",
+	syscall.SQUASHFS_MAGIC:        "squashfs",
+	syscall.SYSFS_MAGIC:           "sysfs",
+	syscall.TMPFS_MAGIC:           "tmpfs",
+	syscall.UDF_SUPER_MAGIC:       "udf",
+	syscall.XFS_SUPER_MAGIC:       "xfs",
+	zfsSuperMagic:                 "zfs",
+}
+
+// fsTypeName returns the name of the file system type with
+// the given magic number, or the number in hex if unknown.
+func fsTypeName(magic uint32) string {
+	if name, ok := fsTypeNames[magic]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", magic)
+}
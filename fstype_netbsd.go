@@ -0,0 +1,12 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	syscall "golang.org/x/sys/unix"
+)
+
+// statvfsType returns the file system type from fs.
+func statvfsType(fs *syscall.Statvfs_t) string {
+	return syscall.ByteSliceToString(fs.Fstypename[:])
+}
@@ -0,0 +1,19 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	syscall "golang.org/x/sys/unix"
+)
+
+// statvfsType returns the file system type from fs.
+func statvfsType(fs *syscall.Statvfs_t) string {
+	name := make([]byte, 0, len(fs.Basetype))
+	for _, c := range fs.Basetype {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return string(name)
+}
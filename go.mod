@@ -1,8 +1,14 @@
 module github.com/mholt/diskspace
 
-go 1.14
+go 1.21
 
 require (
 	go.uber.org/zap v1.15.0
-	golang.org/x/sys v0.0.0-20200615200032-f1bc736245b1
+	golang.org/x/sys v0.22.0
+)
+
+require (
+	github.com/stretchr/testify v1.9.0 // indirect
+	go.uber.org/atomic v1.6.0 // indirect
+	go.uber.org/multierr v1.5.0 // indirect
 )
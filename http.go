@@ -0,0 +1,63 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ServeHTTP writes the live disk usage of m.Volume along
+// with m's configuration and accumulated stats as JSON,
+// so that m can be mounted as a status endpoint.
+func (m *Maintainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.defaultsOnce.Do(m.setDefaults)
+	if err := m.ensureVolume(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	du, err := m.UsageProvider.Usage(r.Context(), m.usagePath())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if du.All == 0 {
+		http.Error(w, errNoSize.Error(), http.StatusInternalServerError)
+		return
+	}
+	stats := m.Stats()
+	m.statsMu.Lock()
+	threshold := m.Threshold
+	m.statsMu.Unlock()
+
+	status := httpStatus{
+		Volume:          m.volume(),
+		Usage:           du,
+		UsedRatio:       m.usedRatio(du),
+		Threshold:       threshold,
+		TotalCleans:     stats.TotalCleans,
+		TotalFreedBytes: stats.TotalFreedBytes,
+	}
+	if !stats.LastCheck.IsZero() {
+		status.LastCheck = &stats.LastCheck
+	}
+	if stats.LastError != nil {
+		status.LastError = stats.LastError.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+type httpStatus struct {
+	Volume          string     `json:"volume"`
+	Usage           Usage      `json:"usage"`
+	UsedRatio       float64    `json:"used_ratio"`
+	Threshold       float64    `json:"threshold"`
+	LastCheck       *time.Time `json:"last_check,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	TotalCleans     uint64     `json:"total_cleans"`
+	TotalFreedBytes uint64     `json:"total_freed_bytes"`
+}
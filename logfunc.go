@@ -0,0 +1,61 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogFunc receives log entries, for plugging in a logging
+// library other than zap or slog. The level is one of
+// "debug", "info", "warn" or "error", and fields holds the
+// structured context of the entry, keyed by name.
+type LogFunc func(level, msg string, fields map[string]interface{})
+
+// newFuncLogger returns a zap logger that emits its
+// entries through fn.
+func newFuncLogger(fn LogFunc) *zap.Logger {
+	return zap.New(funcCore{fn: fn})
+}
+
+// funcCore is a zapcore.Core that writes to a LogFunc.
+type funcCore struct {
+	fn     LogFunc
+	fields []zapcore.Field
+}
+
+// Enabled reports true for all levels, leaving it to the
+// LogFunc to filter entries.
+func (funcCore) Enabled(zapcore.Level) bool { return true }
+
+func (c funcCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return funcCore{fn: c.fn, fields: all}
+}
+
+func (c funcCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c funcCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.fn(funcLevel(ent.Level), ent.Message, encodeFields(c.fields, fields))
+	return nil
+}
+
+func (funcCore) Sync() error { return nil }
+
+func funcLevel(lvl zapcore.Level) string {
+	switch {
+	case lvl <= zapcore.DebugLevel:
+		return "debug"
+	case lvl == zapcore.InfoLevel:
+		return "info"
+	case lvl == zapcore.WarnLevel:
+		return "warn"
+	default:
+		return "error"
+	}
+}
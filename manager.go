@@ -0,0 +1,115 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Manager maintains disk space on multiple volumes.
+type Manager struct {
+	// The maintainers to run. Each one keeps its
+	// own volume, interval, threshold, and Clean
+	// function.
+	Maintainers []*Maintainer
+}
+
+// Manage runs all maintainers concurrently, so that a
+// slow clean on one volume does not delay checks of the
+// others. It blocks until ctx is cancelled and every
+// maintainer has returned.
+//
+// Maintainers whose volumes are on the same file system
+// (for example, subdirectories or bind mounts of one
+// disk) are coordinated: their checks and cleans take
+// turns, so that the space freed by each cleaner is
+// measured accurately, and checks that happen together
+// share a single reading of disk usage.
+func (mgr *Manager) Manage(ctx context.Context) {
+	mgr.groupByFileSystem()
+
+	var wg sync.WaitGroup
+	for _, m := range mgr.Maintainers {
+		wg.Add(1)
+		go func(m *Maintainer) {
+			defer wg.Done()
+			m.Maintain(ctx)
+		}(m)
+	}
+	wg.Wait()
+}
+
+// groupByFileSystem links maintainers whose volumes
+// are on the same file system.
+func (mgr *Manager) groupByFileSystem() {
+	groups := make(map[string]*sharedFileSystem)
+	for _, m := range mgr.Maintainers {
+		m.defaultsOnce.Do(m.setDefaults)
+		if len(m.MeasureVolumes) > 0 || m.ContainerAware || m.QuotaProject != 0 {
+			// its readings may not be of m.Volume, so can't be shared
+			continue
+		}
+		if _, local := m.UsageProvider.(LocalUsage); !local {
+			// its readings don't come from the file system
+			// itself, so two volumes on it may differ
+			continue
+		}
+		if err := m.ensureVolume(); err != nil {
+			// it may not be mounted yet; Maintain will deal with it
+			continue
+		}
+		id, err := fileSystemID(m.volume())
+		if err != nil {
+			// it may not exist yet; Maintain will deal with it
+			continue
+		}
+		if groups[id] == nil {
+			groups[id] = new(sharedFileSystem)
+		}
+		m.shared = groups[id]
+	}
+}
+
+// sharedFileSystem coordinates maintainers of volumes
+// on the same file system.
+type sharedFileSystem struct {
+	// held for the duration of each check
+	checkMu sync.Mutex
+
+	mu      sync.Mutex
+	usage   Usage
+	readAt  time.Time
+	cleaned bool // whether a clean ran since the last reading
+}
+
+// sharedReadWindow is how recent a reading of disk usage
+// must be for another maintainer of the same file system
+// to reuse it.
+const sharedReadWindow = time.Second
+
+// read returns the disk usage of the shared file system,
+// reusing a recent reading if there has been no clean
+// since.
+func (s *sharedFileSystem) read(read func() (Usage, error)) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.cleaned && !s.readAt.IsZero() && time.Since(s.readAt) < sharedReadWindow {
+		return s.usage, nil
+	}
+	du, err := read()
+	if err != nil {
+		return du, err
+	}
+	s.usage, s.readAt, s.cleaned = du, time.Now(), false
+	return du, nil
+}
+
+// invalidate discards the last reading, since
+// a clean may have changed disk usage.
+func (s *sharedFileSystem) invalidate() {
+	s.mu.Lock()
+	s.cleaned = true
+	s.mu.Unlock()
+}
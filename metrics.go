@@ -0,0 +1,24 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+// Metrics records measurements taken by a Maintainer.
+// Implementations must be safe for concurrent use, since
+// several Maintainers may share one. Measurements are
+// labeled with the volume and the Maintainer's Name.
+type Metrics interface {
+	// RecordUsage is called after every successful
+	// reading of the disk usage of volume.
+	RecordUsage(volume, name string, usage Usage, usedRatio float64)
+
+	// RecordClean is called after every successful
+	// run of Clean with the number of bytes it freed.
+	RecordClean(volume, name string, freed uint64)
+}
+
+// VolumeCloser may be implemented by Metrics that need to
+// finalize or remove a volume's measurements once its
+// Maintainer stops, so that they do not go stale.
+type VolumeCloser interface {
+	CloseVolume(volume, name string)
+}
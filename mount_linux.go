@@ -0,0 +1,167 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// MountPointForDevice returns the path where the block
+// device dev (for example, "/dev/nvme1n1p1") is currently
+// mounted, according to /proc/self/mountinfo. Symbolic
+// links such as /dev/disk/by-uuid/... are resolved. If
+// the device is mounted in more than one place, a mount of
+// the root of the file system is preferred over bind mounts.
+func MountPointForDevice(dev string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(dev); err == nil {
+		dev = resolved
+	}
+
+	mounts, err := mountinfo()
+	if err != nil {
+		return "", err
+	}
+
+	var found string
+	for _, mnt := range mounts {
+		if mnt.source != dev {
+			continue
+		}
+		if mnt.root == "/" {
+			return mnt.mountPoint, nil
+		}
+		if found == "" {
+			found = mnt.mountPoint
+		}
+	}
+	if found == "" {
+		return "", fmt.Errorf("device %s is not mounted", dev)
+	}
+	return found, nil
+}
+
+// deviceForPath returns the source (usually a block
+// device) of the file system containing path.
+func deviceForPath(path string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", err
+	}
+	dev := uint64(st.Dev) // narrower on some platforms
+	devID := fmt.Sprintf("%d:%d", syscall.Major(dev), syscall.Minor(dev))
+
+	mounts, err := mountinfo()
+	if err != nil {
+		return "", err
+	}
+	for _, mnt := range mounts {
+		if mnt.devID == devID {
+			return mnt.source, nil
+		}
+	}
+	return "", fmt.Errorf("no mount found for %s (device %s)", path, devID)
+}
+
+// overlayUpperDir returns the upper directory of the
+// overlay file system containing path, which is where
+// writes to it are stored, or "" if path is not on an
+// overlay file system.
+func overlayUpperDir(path string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", err
+	}
+	dev := uint64(st.Dev) // narrower on some platforms
+	devID := fmt.Sprintf("%d:%d", syscall.Major(dev), syscall.Minor(dev))
+
+	mounts, err := mountinfo()
+	if err != nil {
+		return "", err
+	}
+	for _, mnt := range mounts {
+		if mnt.devID != devID || mnt.fsType != "overlay" {
+			continue
+		}
+		for _, opt := range strings.Split(mnt.superOptions, ",") {
+			if upper, ok := strings.CutPrefix(opt, "upperdir="); ok {
+				return upper, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// mountinfoEntry is a line of /proc/self/mountinfo.
+type mountinfoEntry struct {
+	devID        string // major:minor
+	root         string
+	mountPoint   string
+	fsType       string
+	source       string
+	superOptions string
+}
+
+// mountinfo reads /proc/self/mountinfo.
+func mountinfo() ([]mountinfoEntry, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountinfoEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// see proc(5); optional fields end with a "-"
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || sep+2 >= len(fields) {
+			continue
+		}
+		mnt := mountinfoEntry{
+			devID:      fields[2],
+			root:       unescapeMountinfo(fields[3]),
+			mountPoint: unescapeMountinfo(fields[4]),
+			fsType:     fields[sep+1],
+			source:     unescapeMountinfo(fields[sep+2]),
+		}
+		if sep+3 < len(fields) {
+			mnt.superOptions = unescapeMountinfo(fields[sep+3])
+		}
+		mounts = append(mounts, mnt)
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountinfo decodes the octal escapes (such as
+// \040 for a space) used in /proc/self/mountinfo.
+func unescapeMountinfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
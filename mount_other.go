@@ -0,0 +1,24 @@
+// Copyright 2020 Matthew Holt
+
+//go:build !linux
+// +build !linux
+
+package diskspace
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// MountPointForDevice returns the path where the block
+// device dev is currently mounted. It is only supported
+// on Linux.
+func MountPointForDevice(dev string) (string, error) {
+	return "", fmt.Errorf("finding mount points of devices is not supported on %s", runtime.GOOS)
+}
+
+// overlayUpperDir returns "", since overlay file systems
+// are only recognized on Linux.
+func overlayUpperDir(path string) (string, error) {
+	return "", nil
+}
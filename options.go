@@ -0,0 +1,269 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Option configures a Maintainer.
+type Option func(*Maintainer) error
+
+// NewMaintainer returns a new Maintainer configured with
+// opts. Unlike configuring a Maintainer directly, invalid
+// configuration results in an error rather than defaults
+// or a panic later on.
+func NewMaintainer(opts ...Option) (*Maintainer, error) {
+	m := new(Maintainer)
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+	if !m.canRun() {
+		return nil, fmt.Errorf("a clean function is required unless monitoring only")
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	m.defaultsOnce.Do(m.setDefaults)
+	if !m.WaitForVolume {
+		if err := m.resolveVolume(); err != nil {
+			return nil, err
+		}
+		if _, err := m.readVolumes(context.Background()); err != nil {
+			return nil, volumeError{m.Volume, err}
+		}
+	}
+	return m, nil
+}
+
+// validate returns an error if any ratio or percentage
+// in m's configuration is out of range, rather than
+// letting it be silently replaced by a default. A ratio
+// given as a percentage (like 90) is the usual mistake.
+func (m *Maintainer) validate() error {
+	if m.Threshold != 0 && m.ThresholdPercent != 0 {
+		return fmt.Errorf("only one of Threshold and ThresholdPercent may be set")
+	}
+	if m.Schedule != "" {
+		if m.CheckInterval != 0 {
+			return fmt.Errorf("only one of Schedule and CheckInterval may be set")
+		}
+		if _, err := parseSchedule(m.Schedule); err != nil {
+			return err
+		}
+	}
+	if m.ThresholdPercent < 0 || m.ThresholdPercent >= 100 {
+		return fmt.Errorf("ThresholdPercent must be between 0 and 100, exclusive: %d", m.ThresholdPercent)
+	}
+	if m.Smoothing < 0 || m.Smoothing > 1 {
+		return fmt.Errorf("Smoothing must be between 0 and 1: %v", m.Smoothing)
+	}
+	type ratio struct {
+		name  string
+		value float64
+	}
+	ratios := []ratio{
+		{"Threshold", m.Threshold},
+		{"AlertThreshold", m.AlertThreshold},
+		{"InodeThreshold", m.InodeThreshold},
+		{"LowWaterMark", m.LowWaterMark},
+		{"MinFreeRatio", m.MinFreeRatio},
+		{"MinRetainRatio", m.MinRetainRatio},
+	}
+	for _, r := range m.Rules {
+		ratios = append(ratios, ratio{"Rule.Threshold", r.Threshold})
+	}
+	for _, r := range ratios {
+		if r.value < 0 || r.value >= 1 {
+			if r.value >= 1 && r.value < 100 {
+				return fmt.Errorf("%s must be a ratio between 0 and 1, not a percentage: %v", r.name, r.value)
+			}
+			return fmt.Errorf("%s must be between 0 and 1: %v", r.name, r.value)
+		}
+	}
+	return nil
+}
+
+// WithVolume sets the volume to maintain.
+func WithVolume(volume string) Option {
+	return func(m *Maintainer) error {
+		if volume == "" {
+			return fmt.Errorf("volume must not be empty")
+		}
+		m.Volume = volume
+		return nil
+	}
+}
+
+// WithDevice sets the block device whose mount
+// point is the volume to maintain.
+func WithDevice(dev string) Option {
+	return func(m *Maintainer) error {
+		if dev == "" {
+			return fmt.Errorf("device must not be empty")
+		}
+		m.Device = dev
+		return nil
+	}
+}
+
+// WithWaitForVolume makes maintenance wait for the
+// volume to become available instead of failing if
+// it does not exist.
+func WithWaitForVolume() Option {
+	return func(m *Maintainer) error {
+		m.WaitForVolume = true
+		return nil
+	}
+}
+
+// WithThreshold sets the ratio of used/total space
+// above which disk cleaning is triggered. It must
+// be between 0 and 1, exclusive.
+func WithThreshold(threshold float64) Option {
+	return func(m *Maintainer) error {
+		if threshold <= 0 || threshold >= 1 {
+			return fmt.Errorf("threshold must be between 0 and 1, exclusive: %v", threshold)
+		}
+		m.Threshold = threshold
+		return nil
+	}
+}
+
+// WithThresholdPercent sets the percentage of used/total
+// space above which disk cleaning is triggered. It must
+// be between 0 and 100, exclusive.
+func WithThresholdPercent(percent int) Option {
+	return func(m *Maintainer) error {
+		if percent <= 0 || percent >= 100 {
+			return fmt.Errorf("threshold percent must be between 0 and 100, exclusive: %d", percent)
+		}
+		m.ThresholdPercent = percent
+		return nil
+	}
+}
+
+// WithCheckInterval sets how often to check disk usage.
+func WithCheckInterval(interval time.Duration) Option {
+	return func(m *Maintainer) error {
+		if interval <= 0 {
+			return fmt.Errorf("check interval must be positive: %s", interval)
+		}
+		m.CheckInterval = interval
+		return nil
+	}
+}
+
+// WithSchedule sets a cron expression for when to check
+// disk usage, instead of a fixed interval.
+func WithSchedule(spec string) Option {
+	return func(m *Maintainer) error {
+		if _, err := parseSchedule(spec); err != nil {
+			return err
+		}
+		m.Schedule = spec
+		return nil
+	}
+}
+
+// WithClean sets the function that cleans up disk space.
+func WithClean(clean func(ctx context.Context) error) Option {
+	return func(m *Maintainer) error {
+		if clean == nil {
+			return fmt.Errorf("clean function must not be nil")
+		}
+		m.Clean = clean
+		return nil
+	}
+}
+
+// WithCleanBytes sets the function that cleans up disk
+// space given the number of bytes that need to be freed.
+func WithCleanBytes(clean func(ctx context.Context, needed uint64) error) Option {
+	return func(m *Maintainer) error {
+		if clean == nil {
+			return fmt.Errorf("clean function must not be nil")
+		}
+		m.CleanBytes = clean
+		return nil
+	}
+}
+
+// WithMonitorOnly makes m only monitor disk usage and
+// never clean, so no clean function is needed.
+func WithMonitorOnly() Option {
+	return func(m *Maintainer) error {
+		m.MonitorOnly = true
+		return nil
+	}
+}
+
+// WithLogger sets the logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(m *Maintainer) error {
+		if logger == nil {
+			return fmt.Errorf("logger must not be nil")
+		}
+		m.Logger = logger
+		return nil
+	}
+}
+
+// WithSlogLogger sets a standard library logger. It is
+// used only if no zap logger is configured.
+func WithSlogLogger(logger *slog.Logger) Option {
+	return func(m *Maintainer) error {
+		if logger == nil {
+			return fmt.Errorf("logger must not be nil")
+		}
+		m.SlogLogger = logger
+		return nil
+	}
+}
+
+// WithLogFunc sets a function to receive log entries. It
+// is used only if no zap or slog logger is configured.
+func WithLogFunc(fn LogFunc) Option {
+	return func(m *Maintainer) error {
+		if fn == nil {
+			return fmt.Errorf("log function must not be nil")
+		}
+		m.LogFunc = fn
+		return nil
+	}
+}
+
+// WithPercentPrecision sets the number of decimal places
+// of percentages in logs and from UsedPercent. Unlike
+// setting PercentPrecision directly, 0 gives whole
+// percentages rather than the default.
+func WithPercentPrecision(decimals int) Option {
+	return func(m *Maintainer) error {
+		if decimals < 0 {
+			return fmt.Errorf("percent precision must not be negative: %d", decimals)
+		}
+		m.PercentPrecision = decimals
+		m.percentPrecisionSet = true
+		return nil
+	}
+}
+
+// WithCleaners sets cleaners to run in escalating order.
+func WithCleaners(cleaners ...Cleaner) Option {
+	return func(m *Maintainer) error {
+		for i, c := range cleaners {
+			if c.Clean == nil && c.CleanBytes == nil {
+				return fmt.Errorf("cleaner %d: clean function must not be nil", i)
+			}
+		}
+		m.Cleaners = cleaners
+		return nil
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/mholt/diskspace/otel
+
+go 1.21
+
+require (
+	github.com/mholt/diskspace v0.0.0-20261014135826-29957c874315
+	go.opentelemetry.io/otel v1.29.0
+	go.opentelemetry.io/otel/metric v1.29.0
+)
+
+require (
+	go.uber.org/atomic v1.6.0 // indirect
+	go.uber.org/multierr v1.5.0 // indirect
+	go.uber.org/zap v1.15.0 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+)
+
+// for development within this repository; like any replace
+// directive, it is ignored when this module is a dependency
+replace github.com/mholt/diskspace => ../
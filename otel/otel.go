@@ -0,0 +1,90 @@
+// Copyright 2020 Matthew Holt
+
+// Package otel records diskspace maintenance
+// metrics with OpenTelemetry.
+package otel
+
+import (
+	"context"
+
+	"github.com/mholt/diskspace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics implements diskspace.Metrics using
+// OpenTelemetry instruments with attributes for the
+// volume and the name of its Maintainer.
+type Metrics struct {
+	usedRatio      metric.Float64Gauge
+	availableBytes metric.Int64Gauge
+	cleans         metric.Int64Counter
+	freedBytes     metric.Int64Counter
+}
+
+// New creates the instruments from meter. Instruments
+// are created once; share the returned Metrics among
+// Maintainers rather than calling New for each one.
+func New(meter metric.Meter) (*Metrics, error) {
+	var m Metrics
+	var err error
+	m.usedRatio, err = meter.Float64Gauge("diskspace.used_ratio",
+		metric.WithDescription("Ratio of used/total space on the volume."))
+	if err != nil {
+		return nil, err
+	}
+	m.availableBytes, err = meter.Int64Gauge("diskspace.available",
+		metric.WithDescription("Bytes available to unprivileged users on the volume."),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	m.cleans, err = meter.Int64Counter("diskspace.cleans",
+		metric.WithDescription("Number of successful disk cleans."))
+	if err != nil {
+		return nil, err
+	}
+	m.freedBytes, err = meter.Int64Counter("diskspace.freed",
+		metric.WithDescription("Number of bytes freed by disk cleans."),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// WithMeter configures a Maintainer to record
+// metrics with meter.
+func WithMeter(meter metric.Meter) diskspace.Option {
+	return func(m *diskspace.Maintainer) error {
+		metrics, err := New(meter)
+		if err != nil {
+			return err
+		}
+		m.Metrics = metrics
+		return nil
+	}
+}
+
+// RecordUsage implements diskspace.Metrics.
+func (m *Metrics) RecordUsage(volume, name string, usage diskspace.Usage, usedRatio float64) {
+	ctx, attrs := context.Background(), volumeAttrs(volume, name)
+	m.usedRatio.Record(ctx, usedRatio, attrs)
+	m.availableBytes.Record(ctx, int64(usage.Available), attrs)
+}
+
+// RecordClean implements diskspace.Metrics.
+func (m *Metrics) RecordClean(volume, name string, freed uint64) {
+	ctx, attrs := context.Background(), volumeAttrs(volume, name)
+	m.cleans.Add(ctx, 1, attrs)
+	m.freedBytes.Add(ctx, int64(freed), attrs)
+}
+
+func volumeAttrs(volume, name string) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String("volume", volume),
+		attribute.String("name", name))
+}
+
+// Interface guard
+var _ diskspace.Metrics = (*Metrics)(nil)
@@ -0,0 +1,130 @@
+// Copyright 2020 Matthew Holt
+
+// Package prometheus exports diskspace maintenance
+// metrics to Prometheus.
+package prometheus
+
+import (
+	"errors"
+
+	"github.com/mholt/diskspace"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements diskspace.Metrics using
+// Prometheus collectors labeled by volume and by the
+// name of its Maintainer.
+type Metrics struct {
+	usedRatio      *prom.GaugeVec
+	availableBytes *prom.GaugeVec
+	cleans         *prom.CounterVec
+	freedBytes     *prom.CounterVec
+}
+
+// New returns metrics registered with reg. It is safe to
+// call New more than once with the same registerer, so
+// that several Maintainers can share the same collectors.
+func New(reg prom.Registerer) (*Metrics, error) {
+	labels := []string{"volume", "name"}
+	m := &Metrics{
+		usedRatio: prom.NewGaugeVec(prom.GaugeOpts{
+			Namespace: "diskspace",
+			Name:      "used_ratio",
+			Help:      "Ratio of used/total space on the volume.",
+		}, labels),
+		availableBytes: prom.NewGaugeVec(prom.GaugeOpts{
+			Namespace: "diskspace",
+			Name:      "available_bytes",
+			Help:      "Bytes available to unprivileged users on the volume.",
+		}, labels),
+		cleans: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: "diskspace",
+			Name:      "cleans_total",
+			Help:      "Number of successful disk cleans.",
+		}, labels),
+		freedBytes: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: "diskspace",
+			Name:      "freed_bytes_total",
+			Help:      "Number of bytes freed by disk cleans.",
+		}, labels),
+	}
+	var err error
+	if m.usedRatio, err = register(reg, m.usedRatio); err != nil {
+		return nil, err
+	}
+	if m.availableBytes, err = register(reg, m.availableBytes); err != nil {
+		return nil, err
+	}
+	if m.cleans, err = registerCounter(reg, m.cleans); err != nil {
+		return nil, err
+	}
+	if m.freedBytes, err = registerCounter(reg, m.freedBytes); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WithMetrics configures a Maintainer to record
+// metrics with reg.
+func WithMetrics(reg prom.Registerer) diskspace.Option {
+	return func(m *diskspace.Maintainer) error {
+		metrics, err := New(reg)
+		if err != nil {
+			return err
+		}
+		m.Metrics = metrics
+		return nil
+	}
+}
+
+// RecordUsage implements diskspace.Metrics.
+func (m *Metrics) RecordUsage(volume, name string, usage diskspace.Usage, usedRatio float64) {
+	m.usedRatio.WithLabelValues(volume, name).Set(usedRatio)
+	m.availableBytes.WithLabelValues(volume, name).Set(float64(usage.Available))
+}
+
+// RecordClean implements diskspace.Metrics.
+func (m *Metrics) RecordClean(volume, name string, freed uint64) {
+	m.cleans.WithLabelValues(volume, name).Inc()
+	m.freedBytes.WithLabelValues(volume, name).Add(float64(freed))
+}
+
+// CloseVolume implements diskspace.VolumeCloser. It
+// removes the volume's gauges, which would otherwise keep
+// reporting the last reading; counters are kept.
+func (m *Metrics) CloseVolume(volume, name string) {
+	m.usedRatio.DeleteLabelValues(volume, name)
+	m.availableBytes.DeleteLabelValues(volume, name)
+}
+
+func register(reg prom.Registerer, g *prom.GaugeVec) (*prom.GaugeVec, error) {
+	if err := reg.Register(g); err != nil {
+		var are prom.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(*prom.GaugeVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+func registerCounter(reg prom.Registerer, c *prom.CounterVec) (*prom.CounterVec, error) {
+	if err := reg.Register(c); err != nil {
+		var are prom.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(*prom.CounterVec); ok {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Interface guards
+var (
+	_ diskspace.Metrics      = (*Metrics)(nil)
+	_ diskspace.VolumeCloser = (*Metrics)(nil)
+)
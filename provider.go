@@ -0,0 +1,36 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import "context"
+
+// UsageProvider reads the disk usage of volumes. The
+// default reads the local file system, but another could
+// read it from a remote host, for example, to maintain
+// disk space elsewhere. Implementations should return
+// promptly once ctx is done.
+type UsageProvider interface {
+	Usage(ctx context.Context, volume string) (Usage, error)
+}
+
+// LocalUsage is a UsageProvider that reads the disk usage
+// of local volumes with DiskUsage.
+type LocalUsage struct{}
+
+// Usage implements UsageProvider.
+func (LocalUsage) Usage(ctx context.Context, volume string) (Usage, error) {
+	return usageFunc(DiskUsage).Usage(ctx, volume)
+}
+
+// usageFunc adapts a function like DiskUsage to a
+// UsageProvider.
+type usageFunc func(path string) (Usage, error)
+
+// Usage calls f for volume, but returns early if ctx has
+// a deadline and it passes first.
+func (f usageFunc) Usage(ctx context.Context, volume string) (Usage, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return f(volume)
+	}
+	return usageContext(ctx, volume, f)
+}
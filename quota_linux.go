@@ -0,0 +1,110 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	syscall "golang.org/x/sys/unix"
+)
+
+// From linux/quota.h, which x/sys does not provide.
+const (
+	qGetQuota   = 0x800007
+	prjQuota    = 2
+	subCmdShift = 8
+	qifBlkSize  = 1024 // size of the blocks in limits
+)
+
+// ifDqblk is struct if_dqblk from linux/quota.h.
+type ifDqblk struct {
+	bHardLimit uint64
+	bSoftLimit uint64
+	curSpace   uint64
+	iHardLimit uint64
+	iSoftLimit uint64
+	curInodes  uint64
+	bTime      uint64
+	iTime      uint64
+	valid      uint32
+}
+
+// ProjectQuotaUsage returns the disk usage of the project
+// quota with the given ID on the file system containing
+// path, such as an XFS or ext4 project quota set up for a
+// directory tree. The quota's limit takes the place of
+// the size of the volume, and space is available up to
+// the limit or the free space on the file system,
+// whichever is less. If project quotas are not enabled,
+// or the project has no limit, it returns the usage of
+// the whole file system, like DiskUsage. Reading the
+// quotas of other users' projects requires privileges.
+func ProjectQuotaUsage(path string, project uint32) (Usage, error) {
+	du, err := DiskUsage(path)
+	if err != nil {
+		return Usage{}, err
+	}
+	dev, err := deviceForPath(path)
+	if err != nil {
+		return Usage{}, err
+	}
+	devPtr, err := syscall.BytePtrFromString(dev)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	var dq ifDqblk
+	const cmd uint32 = qGetQuota<<subCmdShift | prjQuota
+	_, _, errno := syscall.Syscall6(syscall.SYS_QUOTACTL,
+		uintptr(cmd), uintptr(unsafe.Pointer(devPtr)), uintptr(project),
+		uintptr(unsafe.Pointer(&dq)), 0, 0)
+	if errno != 0 {
+		// quotas off, no such quota, or not supported
+		if errors.Is(errno, syscall.ESRCH) || errors.Is(errno, syscall.ENOENT) ||
+			errors.Is(errno, syscall.ENOSYS) || errors.Is(errno, syscall.EOPNOTSUPP) {
+			return du, nil
+		}
+		return Usage{}, fmt.Errorf("reading quota of project %d on %s: %w", project, dev, errno)
+	}
+
+	limit := dq.bHardLimit
+	if limit == 0 {
+		limit = dq.bSoftLimit
+	}
+	if limit == 0 {
+		return du, nil
+	}
+	limit *= qifBlkSize
+
+	quota := du
+	quota.All = limit
+	quota.Used = dq.curSpace
+	quota.Free = 0
+	if dq.curSpace < limit {
+		quota.Free = limit - dq.curSpace
+	}
+	if quota.Free > du.Free {
+		quota.Free = du.Free
+	}
+	quota.Available = quota.Free
+	if quota.Available > du.Available {
+		quota.Available = du.Available
+	}
+
+	inodeLimit := dq.iHardLimit
+	if inodeLimit == 0 {
+		inodeLimit = dq.iSoftLimit
+	}
+	if inodeLimit > 0 {
+		quota.Inodes = inodeLimit
+		quota.InodesUsed = dq.curInodes
+		quota.InodesFree = 0
+		if dq.curInodes < inodeLimit {
+			quota.InodesFree = inodeLimit - dq.curInodes
+		}
+	}
+
+	return quota, nil
+}
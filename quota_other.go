@@ -0,0 +1,18 @@
+// Copyright 2020 Matthew Holt
+
+//go:build !linux
+// +build !linux
+
+package diskspace
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// ProjectQuotaUsage returns the disk usage of a project
+// quota on the file system containing path. It is only
+// supported on Linux.
+func ProjectQuotaUsage(path string, project uint32) (Usage, error) {
+	return Usage{}, fmt.Errorf("project quotas are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// checkRecord is one line written to ResultWriter.
+type checkRecord struct {
+	Time      time.Time `json:"time"`
+	Volume    string    `json:"volume"`
+	Usage     *Usage    `json:"usage,omitempty"`
+	UsedRatio float64   `json:"used_ratio,omitempty"`
+	Cleaned   bool      `json:"cleaned"`
+	Freed     uint64    `json:"freed"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// writeResult writes the outcome of a check, which read
+// du, to m.ResultWriter as a line of JSON. It must be
+// called with m.mu held, which serializes writes. Write
+// errors are logged but otherwise ignored.
+func (m *Maintainer) writeResult(result CheckResult, du Usage, err error) {
+	if m.ResultWriter == nil {
+		return
+	}
+	rec := checkRecord{
+		Time:    m.stats.LastCheck,
+		Volume:  m.Volume,
+		Cleaned: result.Cleaned,
+		Freed:   result.Freed,
+	}
+	// a volume with no size has no ratio, and its
+	// reading was not recorded as the last usage
+	if m.checkFailures == 0 && du.All > 0 {
+		// the last usage is after cleaning, if any
+		usage := m.lastUsage
+		rec.Usage = &usage
+		rec.UsedRatio = m.usedRatio(usage)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		m.Logger.Error("encoding check result", zap.Error(err))
+		return
+	}
+	line = append(line, '\n')
+
+	// a single write keeps lines intact
+	if _, err := m.ResultWriter.Write(line); err != nil {
+		m.Logger.Warn("writing check result", zap.Error(err))
+	}
+}
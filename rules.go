@@ -0,0 +1,130 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Rule runs a cleaner whenever disk usage reaches its
+// threshold, independently of other rules and of Clean.
+type Rule struct {
+	// The ratio of used/total space at or
+	// above which Clean runs.
+	Threshold float64
+
+	// The function that cleans up disk space.
+	Clean func(ctx context.Context) error
+}
+
+// applyRules runs, at most once each, the rules whose
+// thresholds are met by du, highest threshold first. It
+// returns the usage after they ran, or du if none did.
+// All rules are evaluated against du, so one rule freeing
+// space does not stop another whose threshold was met.
+// Like Clean, rules do not run while coolingDown, given
+// sinceClean, the time since the last clean.
+func (m *Maintainer) applyRules(ctx context.Context, du Usage, result *CheckResult, sinceClean time.Duration, coolingDown bool) (Usage, error) {
+	usedRatio := m.usedRatio(du)
+
+	var rules []Rule
+	for _, r := range m.Rules {
+		if usedRatio >= r.Threshold {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return du, nil
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Threshold > rules[j].Threshold
+	})
+
+	if m.SkipReadOnly && du.ReadOnly {
+		return du, nil
+	}
+	if m.ShouldClean != nil && !m.ShouldClean(du) {
+		m.Logger.Info("clean skipped by policy",
+			zap.String("volume", m.Volume),
+			zap.Float64("used_ratio", usedRatio))
+		return du, nil
+	}
+
+	if coolingDown {
+		m.Logger.Info("skipping rules during cooldown",
+			zap.Duration("since_last_clean", sinceClean),
+			zap.Duration("cooldown", m.CleanCooldown))
+		return du, nil
+	}
+
+	if m.DryRun {
+		for _, r := range rules {
+			m.Logger.Warn("would run rule",
+				zap.Float64("rule_threshold", r.Threshold),
+				zap.Float64("used_ratio", usedRatio))
+			m.emit(Event{Type: WouldClean, UsedRatio: usedRatio})
+		}
+		return du, nil
+	}
+
+	after := du
+	err := m.withCleanHooks(du, func() error {
+		var err error
+		after, err = m.runRules(ctx, du, rules, result)
+		return err
+	})
+	return after, err
+}
+
+// runRules runs rules, which are met by du, and returns
+// the usage after they ran.
+func (m *Maintainer) runRules(ctx context.Context, du Usage, rules []Rule, result *CheckResult) (Usage, error) {
+	usedRatio := m.usedRatio(du)
+
+	var errs []error
+	var ran bool
+	for _, r := range rules {
+		err := m.runClean(ctx, r.Clean)
+		if m.shared != nil {
+			m.shared.invalidate()
+		}
+		if err != nil {
+			m.emit(Event{Type: CleanFailed, UsedRatio: usedRatio, Err: err})
+			errs = append(errs, fmt.Errorf("rule at %.2f: %w", r.Threshold, err))
+			continue
+		}
+		ran = true
+		m.lastClean = m.clock.now()
+		m.statsMu.Lock()
+		m.stats.TotalCleans++
+		m.statsMu.Unlock()
+		result.Cleaned = true
+		m.infoLogger()("ran cleaning rule",
+			zap.Float64("rule_threshold", r.Threshold),
+			zap.Float64("used_ratio", usedRatio))
+	}
+
+	if ran {
+		newDu, err := m.readUsage(ctx)
+		if err != nil {
+			m.emit(Event{Type: CheckFailed, Err: err})
+			return du, checkError{err}
+		}
+		freed := m.recordCleaned(du, newDu, result)
+		m.infoLogger()("disk space cleaned by rules",
+			zap.Uint64("used_mb", newDu.Used/MB),
+			zap.String("freed", FormatBytes(freed)))
+		du = newDu
+	}
+
+	if len(errs) > 0 {
+		return du, cleanError{fmt.Errorf("clean: %w", errors.Join(errs...))}
+	}
+	return du, nil
+}
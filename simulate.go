@@ -0,0 +1,114 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import "time"
+
+// UsageSample is a reading of disk usage at a point in
+// time, such as from a recorded trace.
+type UsageSample struct {
+	Time  time.Time
+	Usage Usage
+}
+
+// SimConfig configures Simulate. The fields mean the same
+// as those of Maintainer, with the same defaults.
+type SimConfig struct {
+	Threshold        float64
+	LowWaterMark     float64
+	MinFree          uint64
+	MinFreeRatio     float64
+	UsageBasis       UsageBasis
+	CleanCooldown    time.Duration
+	MaxCleanAttempts int
+
+	// The number of bytes each run of Clean is
+	// assumed to free.
+	FreedPerClean uint64
+}
+
+// SimResult is the outcome of Simulate.
+type SimResult struct {
+	// The number of times Clean would have run.
+	Cleans int
+
+	// The total time that usage needed cleaning,
+	// counting from each sample that needed it to
+	// the next sample.
+	TimeAboveThreshold time.Duration
+
+	// The estimated number of bytes freed, assuming
+	// each clean frees SimConfig.FreedPerClean.
+	Freed uint64
+}
+
+// Simulate replays samples, which must be in time order,
+// as the checks of a Maintainer configured by cfg, and
+// reports how often cleaning would have run, which helps
+// with choosing a threshold before deploying it. It uses
+// the same decisions as a Maintainer, but does no I/O:
+// space freed by a simulated clean is subtracted from the
+// usage of all later samples.
+func Simulate(samples []UsageSample, cfg SimConfig) SimResult {
+	m := &Maintainer{
+		Threshold:        cfg.Threshold,
+		LowWaterMark:     cfg.LowWaterMark,
+		MinFree:          cfg.MinFree,
+		MinFreeRatio:     cfg.MinFreeRatio,
+		UsageBasis:       cfg.UsageBasis,
+		CleanCooldown:    cfg.CleanCooldown,
+		MaxCleanAttempts: cfg.MaxCleanAttempts,
+	}
+	m.defaultsOnce.Do(m.setDefaults)
+
+	target := m.Threshold
+	if m.LowWaterMark > 0 {
+		target = m.LowWaterMark
+	}
+
+	var result SimResult
+	var lastClean time.Time
+	for i, sample := range samples {
+		du := sample.Usage.afterFreeing(result.Freed)
+		if du.All == 0 || !m.needsCleaning(du, m.Threshold) {
+			continue
+		}
+		if i+1 < len(samples) {
+			result.TimeAboveThreshold += samples[i+1].Time.Sub(sample.Time)
+		}
+		if m.CleanCooldown > 0 && !lastClean.IsZero() &&
+			sample.Time.Sub(lastClean) < m.CleanCooldown {
+			continue
+		}
+
+		// like Maintainer.clean, Clean runs again only
+		// to reach the low-water mark
+		for attempt := 1; ; attempt++ {
+			freed := cfg.FreedPerClean
+			if freed > du.Used {
+				freed = du.Used
+			}
+			result.Cleans++
+			result.Freed += freed
+			du = du.afterFreeing(freed)
+			if freed == 0 || m.LowWaterMark <= 0 ||
+				attempt >= m.MaxCleanAttempts || !m.needsCleaning(du, target) {
+				break
+			}
+		}
+		lastClean = sample.Time
+	}
+	return result
+}
+
+// afterFreeing returns u as it would be after freeing n
+// bytes, or as much of n as is used.
+func (u Usage) afterFreeing(n uint64) Usage {
+	if n > u.Used {
+		n = u.Used
+	}
+	u.Used -= n
+	u.Free += n
+	u.Available += n
+	return u
+}
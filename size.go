@@ -0,0 +1,166 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FormatBytes renders n as a human-readable size in
+// binary units (1 KiB = 1024 bytes) using the largest
+// unit that keeps the value at least 1, rounded to two
+// decimal places; for example, "3.81 TiB". Binary units
+// match the size constants, like KB and MB.
+func FormatBytes(n uint64) string {
+	return formatBytes(n, byteUnits)
+}
+
+// FormatBytesSI is like FormatBytes, but uses decimal
+// units (1 kB = 1000 bytes), as reported by "df -H" and
+// most storage vendors and cloud providers; for example,
+// "4.19 TB" for the same size as "3.81 TiB".
+func FormatBytesSI(n uint64) string {
+	return formatBytes(n, siByteUnits)
+}
+
+func formatBytes(n uint64, units []byteUnit) string {
+	for _, u := range units {
+		if n >= u.size {
+			return fmt.Sprintf("%.2f %s", float64(n)/float64(u.size), u.name)
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+type byteUnit struct {
+	size uint64
+	name string
+}
+
+// byteUnits is ordered from largest to smallest.
+var byteUnits = []byteUnit{
+	{EB, "EiB"},
+	{PB, "PiB"},
+	{TB, "TiB"},
+	{GB, "GiB"},
+	{MB, "MiB"},
+	{KB, "KiB"},
+}
+
+// siByteUnits is ordered from largest to smallest.
+var siByteUnits = []byteUnit{
+	{1e18, "EB"},
+	{1e15, "PB"},
+	{1e12, "TB"},
+	{1e9, "GB"},
+	{1e6, "MB"},
+	{1e3, "kB"},
+}
+
+// ParseSize parses a human-readable size such as "500GB",
+// "1.5 TiB", or "100mb" into a number of bytes. Units are
+// case-insensitive and always binary, whether written as
+// KB or KiB (1024 bytes), so that the output of FormatBytes
+// can be parsed; a number without a unit is a number of
+// bytes.
+func ParseSize(s string) (uint64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+
+	// split the number from the unit
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(str)
+	}
+	num, unit := str[:i], strings.TrimSpace(str[i:])
+	if num == "" {
+		return 0, fmt.Errorf("invalid size %q: missing number", s)
+	}
+
+	mult := uint64(1)
+	if unit != "" && unit != "B" {
+		found := false
+		for _, u := range byteUnits {
+			// the IEC name, or the same without the "i"
+			name := strings.ToUpper(u.name)
+			if unit == name || unit == strings.Replace(name, "I", "", 1) {
+				mult, found = u.size, true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+		}
+	}
+
+	// avoid floating point for whole numbers so that
+	// large byte counts stay exact
+	if !strings.Contains(num, ".") {
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid size %q: %w", s, err)
+		}
+		if n > math.MaxUint64/mult {
+			return 0, fmt.Errorf("invalid size %q: too large", s)
+		}
+		return n * mult, nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	bytes := f * float64(mult)
+	if bytes >= math.MaxUint64 {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	return uint64(bytes), nil
+}
+
+// ByteCount is a number of bytes. It prints in human-
+// readable form, and can be decoded from JSON as either a
+// number of bytes or a size string such as "3.5GB", which
+// makes it convenient in configuration. Convert to and
+// from uint64 to use it with fields such as MinFree.
+type ByteCount uint64
+
+// String returns b formatted by FormatBytes.
+func (b ByteCount) String() string {
+	return FormatBytes(uint64(b))
+}
+
+// MarshalJSON encodes b as a human-readable size string
+// if that string decodes to exactly b; otherwise, it
+// encodes b as a number, so that no precision is lost.
+func (b ByteCount) MarshalJSON() ([]byte, error) {
+	str := b.String()
+	if n, err := ParseSize(str); err == nil && n == uint64(b) {
+		return json.Marshal(str)
+	}
+	return json.Marshal(uint64(b))
+}
+
+// UnmarshalJSON decodes either a number of bytes or a
+// size string accepted by ParseSize.
+func (b *ByteCount) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		var n uint64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("byte count must be a number or size string: %w", err)
+		}
+		*b = ByteCount(n)
+		return nil
+	}
+	n, err := ParseSize(str)
+	if err != nil {
+		return err
+	}
+	*b = ByteCount(n)
+	return nil
+}
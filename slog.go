@@ -0,0 +1,88 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newSlogLogger returns a zap logger that emits its
+// entries through logger.
+func newSlogLogger(logger *slog.Logger) *zap.Logger {
+	return zap.New(slogCore{logger: logger})
+}
+
+// slogCore is a zapcore.Core that writes to a slog.Logger.
+type slogCore struct {
+	logger *slog.Logger
+	fields []zapcore.Field
+}
+
+func (c slogCore) Enabled(lvl zapcore.Level) bool {
+	return c.logger.Enabled(context.Background(), slogLevel(lvl))
+}
+
+func (c slogCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return slogCore{logger: c.logger, fields: all}
+}
+
+func (c slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	values := encodeFields(c.fields, fields)
+
+	// map iteration order is random; keep output stable
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, values[k]))
+	}
+
+	c.logger.LogAttrs(context.Background(), slogLevel(ent.Level), ent.Message, attrs...)
+	return nil
+}
+
+func (slogCore) Sync() error { return nil }
+
+// encodeFields returns the values of the given fields,
+// keyed by field name.
+func encodeFields(fieldSets ...[]zapcore.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fields := range fieldSets {
+		for _, f := range fields {
+			f.AddTo(enc)
+		}
+	}
+	return enc.Fields
+}
+
+func slogLevel(lvl zapcore.Level) slog.Level {
+	switch {
+	case lvl <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case lvl == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case lvl == zapcore.WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 Matthew Holt
+
+//go:build freebsd || dragonfly
+// +build freebsd dragonfly
+
+package diskspace
+
+import (
+	syscall "golang.org/x/sys/unix"
+)
+
+func diskUsage(path string) (diskStatus, error) {
+	fs := syscall.Statfs_t{}
+	err := syscall.Statfs(path, &fs)
+	if err != nil {
+		return diskStatus{}, err
+	}
+	bsize := uint64(fs.Bsize)
+	disk := diskStatus{
+		all: uint64(fs.Blocks) * bsize,
+		// Bavail is signed, and negative when the
+		// reserved space is being used
+		available: nonNegative(int64(fs.Bavail)) * bsize,
+		free:      uint64(fs.Bfree) * bsize,
+		files:     uint64(fs.Files),
+		filesFree: nonNegative(int64(fs.Ffree)),
+		readOnly:  fs.Flags&syscall.MNT_RDONLY != 0,
+		fsType:    syscall.ByteSliceToString(fs.Fstypename[:]),
+	}
+	disk.used = disk.all - disk.free
+	disk.filesUsed = disk.files - disk.filesFree
+	return disk, nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	syscall "golang.org/x/sys/unix"
+)
+
+func diskUsage(path string) (diskStatus, error) {
+	fs := syscall.Statfs_t{}
+	err := syscall.Statfs(path, &fs)
+	if err != nil {
+		return diskStatus{}, err
+	}
+	disk := diskStatus{
+		all:       fs.Blocks * uint64(fs.Bsize),
+		available: fs.Bavail * uint64(fs.Bsize),
+		free:      fs.Bfree * uint64(fs.Bsize),
+		files:     fs.Files,
+		filesFree: fs.Ffree,
+		readOnly:  fs.Flags&syscall.MNT_RDONLY != 0,
+		fsType:    syscall.ByteSliceToString(fs.Fstypename[:]),
+	}
+	disk.used = disk.all - disk.free
+	disk.filesUsed = disk.files - disk.filesFree
+	return disk, nil
+}
@@ -3,15 +3,9 @@
 package diskspace
 
 import (
-	"runtime"
-
 	syscall "golang.org/x/sys/unix"
 )
 
-type diskStatus struct {
-	all, available, free, used uint64
-}
-
 // Source: https://gist.github.com/ttys3/21e2a1215cf1905ab19ddcec03927c75
 func diskUsage(path string) (diskStatus, error) {
 	fs := syscall.Statfs_t{}
@@ -23,12 +17,15 @@ func diskUsage(path string) (diskStatus, error) {
 		all:       fs.Blocks * uint64(fs.Bsize),
 		available: fs.Bavail * uint64(fs.Bsize),
 		free:      fs.Bfree * uint64(fs.Bsize),
+		files:     fs.Files,
+		filesFree: fs.Ffree,
+		readOnly:  fs.Flags&syscall.ST_RDONLY != 0,
+		// the width and signedness of Type vary
+		// by architecture, but magic numbers are
+		// 32 bits
+		fsType: fsTypeName(uint32(fs.Type)),
 	}
-	if runtime.GOOS == "darwin" {
-		// not sure why mac is different but whatevs
-		disk.used = disk.all - disk.available
-	} else {
-		disk.used = disk.all - disk.free
-	}
+	disk.used = disk.all - disk.free
+	disk.filesUsed = disk.files - disk.filesFree
 	return disk, nil
 }
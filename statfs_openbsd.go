@@ -0,0 +1,30 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	syscall "golang.org/x/sys/unix"
+)
+
+func diskUsage(path string) (diskStatus, error) {
+	fs := syscall.Statfs_t{}
+	err := syscall.Statfs(path, &fs)
+	if err != nil {
+		return diskStatus{}, err
+	}
+	bsize := uint64(fs.F_bsize)
+	disk := diskStatus{
+		all: fs.F_blocks * bsize,
+		// F_bavail is signed, and negative when the
+		// reserved space is being used
+		available: nonNegative(fs.F_bavail) * bsize,
+		free:      fs.F_bfree * bsize,
+		files:     fs.F_files,
+		filesFree: fs.F_ffree,
+		readOnly:  fs.F_flags&syscall.MNT_RDONLY != 0,
+		fsType:    syscall.ByteSliceToString(fs.F_fstypename[:]),
+	}
+	disk.used = disk.all - disk.free
+	disk.filesUsed = disk.files - disk.filesFree
+	return disk, nil
+}
@@ -0,0 +1,15 @@
+// Copyright 2020 Matthew Holt
+
+//go:build !linux && !darwin && !freebsd && !dragonfly && !openbsd && !netbsd && !solaris && !windows
+// +build !linux,!darwin,!freebsd,!dragonfly,!openbsd,!netbsd,!solaris,!windows
+
+package diskspace
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func diskUsage(path string) (diskStatus, error) {
+	return diskStatus{}, fmt.Errorf("reading disk usage is not supported on %s", runtime.GOOS)
+}
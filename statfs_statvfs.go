@@ -0,0 +1,35 @@
+// Copyright 2020 Matthew Holt
+
+//go:build netbsd || solaris
+// +build netbsd solaris
+
+package diskspace
+
+import (
+	syscall "golang.org/x/sys/unix"
+)
+
+// stRdonly is ST_RDONLY from statvfs(3).
+const stRdonly = 0x1
+
+func diskUsage(path string) (diskStatus, error) {
+	fs := syscall.Statvfs_t{}
+	err := syscall.Statvfs(path, &fs)
+	if err != nil {
+		return diskStatus{}, err
+	}
+	// block counts are in units of the fragment size
+	frsize := uint64(fs.Frsize)
+	disk := diskStatus{
+		all:       uint64(fs.Blocks) * frsize,
+		available: uint64(fs.Bavail) * frsize,
+		free:      uint64(fs.Bfree) * frsize,
+		files:     uint64(fs.Files),
+		filesFree: uint64(fs.Ffree),
+		readOnly:  uint64(fs.Flag)&stRdonly != 0,
+		fsType:    statvfsType(&fs),
+	}
+	disk.used = disk.all - disk.free
+	disk.filesUsed = disk.files - disk.filesFree
+	return disk, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 Matthew Holt
+
+//go:build windows
+// +build windows
+
+package diskspace
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+func diskUsage(path string) (diskStatus, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return diskStatus{}, err
+	}
+	var available, all, free uint64
+	err = windows.GetDiskFreeSpaceEx(pathPtr, &available, &all, &free)
+	if err != nil {
+		return diskStatus{}, err
+	}
+	return diskStatus{
+		all:       all,
+		available: available,
+		free:      free,
+		used:      all - free,
+		fsType:    fileSystemType(path),
+	}, nil
+}
+
+// fileSystemType returns the name of the file system
+// (e.g. "NTFS") containing path, or "" if unknown.
+func fileSystemType(path string) string {
+	root, err := fileSystemID(path)
+	if err != nil {
+		return ""
+	}
+	rootPtr, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return ""
+	}
+	name := make([]uint16, windows.MAX_PATH+1)
+	err = windows.GetVolumeInformation(rootPtr, nil, 0, nil, nil, nil, &name[0], uint32(len(name)))
+	if err != nil {
+		return ""
+	}
+	return windows.UTF16ToString(name)
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import "time"
+
+// Stats is a snapshot of a Maintainer's activity.
+type Stats struct {
+	// When disk usage was last checked. Zero
+	// if it has never been checked.
+	LastCheck time.Time
+
+	// Whether the last check ran Clean.
+	LastCheckCleaned bool
+
+	// The ratio of used/total space as of
+	// the last successful reading.
+	LastUsedRatio float64
+
+	// Space available to unprivileged users,
+	// and free space including any reserved for
+	// privileged users, as of the last successful
+	// reading. The difference between them is
+	// the space reserved on the volume.
+	LastAvailable uint64
+	LastFree      uint64
+
+	// The file system type as of the last
+	// successful reading, if known.
+	FSType string
+
+	// The number of times Clean has
+	// been run successfully.
+	TotalCleans uint64
+
+	// The number of bytes freed by
+	// all cleans.
+	TotalFreedBytes uint64
+
+	// The number of successful cleans that freed
+	// less than MinEffectiveFreedBytes.
+	IneffectiveCleans uint64
+
+	// The error from the last check, if any.
+	LastError error
+
+	// The number of times in a row Clean has
+	// failed; reset when it succeeds.
+	ConsecutiveCleanFailures int
+}
+
+// Stats returns a snapshot of m's activity.
+// It does not wait for a check or clean in progress.
+func (m *Maintainer) Stats() Stats {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	return m.stats
+}
+
+// LastCheckTime returns when disk usage was last checked,
+// or the zero time if it has never been checked. Along
+// with LastCheckCleaned, it is suitable for a simple
+// liveness check.
+func (m *Maintainer) LastCheckTime() time.Time {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	return m.stats.LastCheck
+}
+
+// LastCheckCleaned returns true if the last completed
+// check ran Clean.
+func (m *Maintainer) LastCheckCleaned() bool {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	return m.stats.LastCheckCleaned
+}
+
+// freedBuckets are the upper bounds of the buckets counted
+// by FreedHistogram; cleans that free at least the last
+// bound are counted in one final bucket.
+var freedBuckets = []struct {
+	bound uint64
+	label string
+}{
+	{MB, "<1MiB"},
+	{10 * MB, "<10MiB"},
+	{100 * MB, "<100MiB"},
+	{GB, "<1GiB"},
+	{10 * GB, "<10GiB"},
+	{100 * GB, "<100GiB"},
+}
+
+// freedOverflow labels the bucket for cleans that freed
+// at least the largest bound.
+const freedOverflow = ">=100GiB"
+
+// FreedHistogram returns the number of successful cleans
+// that freed an amount of space within each size bucket,
+// keyed by bucket label (e.g. "<10MiB" counts cleans that
+// freed at least 1 MiB but less than 10 MiB). Every bucket
+// is present in the map, even if its count is zero.
+func (m *Maintainer) FreedHistogram() map[string]uint64 {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	hist := make(map[string]uint64, len(freedBuckets)+1)
+	for i, b := range freedBuckets {
+		hist[b.label] = m.freedHist[i]
+	}
+	hist[freedOverflow] = m.freedHist[len(freedBuckets)]
+	return hist
+}
+
+// recordFreed counts freed in the histogram. m.mu and
+// m.statsMu must be held.
+func (m *Maintainer) recordFreed(freed uint64) {
+	for i, b := range freedBuckets {
+		if freed < b.bound {
+			m.freedHist[i]++
+			return
+		}
+	}
+	m.freedHist[len(freedBuckets)]++
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"math"
+	"time"
+)
+
+// trendSize is how many recent samples are kept
+// for estimating how fast a volume is filling.
+const trendSize = 32
+
+type usageSample struct {
+	time time.Time
+	used uint64
+}
+
+// trend is a ring buffer of recent usage samples.
+type trend struct {
+	samples [trendSize]usageSample
+	next, n int
+	all     uint64
+}
+
+func (t *trend) add(when time.Time, du Usage) {
+	t.samples[t.next] = usageSample{time: when, used: du.Used}
+	t.next = (t.next + 1) % trendSize
+	if t.n < trendSize {
+		t.n++
+	}
+	t.all = du.All
+}
+
+// timeToFull estimates, by linear regression over the
+// samples, how long from now until the volume is full.
+// It returns false if there are too few samples or
+// usage is not growing. Estimates too long for a
+// time.Duration are capped at the longest one.
+func (t *trend) timeToFull(now time.Time) (time.Duration, bool) {
+	if t.n < 2 {
+		return 0, false
+	}
+
+	oldest := t.samples[(t.next-t.n+trendSize)%trendSize]
+	latest := t.samples[(t.next-1+trendSize)%trendSize]
+
+	// x is seconds since the oldest sample; y is bytes used
+	var sumX, sumY, sumXY, sumXX float64
+	for i := 0; i < t.n; i++ {
+		s := t.samples[(t.next-t.n+i+trendSize)%trendSize]
+		x := s.time.Sub(oldest.time).Seconds()
+		y := float64(s.used)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(t.n)
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / denom // bytes per second
+	if slope <= 0 {
+		return 0, false
+	}
+
+	remaining := float64(t.all) - float64(latest.used)
+	secs := remaining/slope - now.Sub(latest.time).Seconds()
+	if secs < 0 {
+		secs = 0
+	}
+	if secs >= math.MaxInt64/float64(time.Second) {
+		// growth is so slow that it would overflow
+		return math.MaxInt64, true
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
+
+// TimeToFull estimates how long until m.Volume is full,
+// based on the trend of recent checks. It returns false
+// if the trend is flat or shrinking, or if there have
+// not been enough checks yet.
+func (m *Maintainer) TimeToFull() (time.Duration, bool) {
+	m.defaultsOnce.Do(m.setDefaults)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.trend.timeToFull(m.clock.now())
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTimeToFullSlowGrowth(t *testing.T) {
+	// a 4 TiB volume growing by 100 bytes per second
+	// won't be full for over a million years
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tr trend
+	for i := 0; i < 10; i++ {
+		tr.add(start.Add(time.Duration(i)*time.Minute), Usage{
+			All:  4 * TB,
+			Used: TB + uint64(i)*60*100,
+		})
+	}
+
+	d, ok := tr.timeToFull(start.Add(9 * time.Minute))
+	if !ok {
+		t.Fatal("expected an estimate for a growing volume")
+	}
+	if d != math.MaxInt64 {
+		t.Errorf("expected the longest duration, got %v", d)
+	}
+}
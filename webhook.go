@@ -0,0 +1,126 @@
+// Copyright 2020 Matthew Holt
+
+package diskspace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// webhookPayload is the JSON body posted to a Webhook.
+type webhookPayload struct {
+	// a summary, which is also what chat services
+	// like Slack display from incoming webhooks
+	Text string `json:"text"`
+
+	Event       string    `json:"event"`
+	Time        time.Time `json:"time"`
+	Name        string    `json:"name"`
+	Volume      string    `json:"volume"`
+	UsedRatio   float64   `json:"used_ratio"`
+	UsedPercent string    `json:"used_percent"`
+	Available   uint64    `json:"available"`
+	Free        uint64    `json:"free"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// webhook posts events to a URL without blocking the
+// caller. At most webhookConcurrency posts are in flight;
+// beyond that, events are dropped.
+type webhook struct {
+	url    string
+	client *http.Client
+	slots  chan struct{}
+	logger *zap.Logger
+}
+
+func newWebhook(url string, logger *zap.Logger) *webhook {
+	return &webhook{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+		slots:  make(chan struct{}, webhookConcurrency),
+		logger: logger,
+	}
+}
+
+// notifyWebhook posts event to m.Webhook, if set and if
+// event is of a type worth notifying about. m.mu must be
+// held.
+func (m *Maintainer) notifyWebhook(event Event) {
+	if m.webhook == nil || (event.Type != ThresholdExceeded && event.Type != CleanFailed) {
+		return
+	}
+	payload := webhookPayload{
+		Event:       event.Type.String(),
+		Time:        event.Time,
+		Name:        m.Name,
+		Volume:      event.Volume,
+		UsedRatio:   event.UsedRatio,
+		UsedPercent: m.formatPercent(event.UsedRatio),
+		Available:   event.Available,
+		Free:        event.Free,
+	}
+	switch event.Type {
+	case ThresholdExceeded:
+		payload.Text = fmt.Sprintf("Disk space on %s is %s used (%s available) and needs cleaning.",
+			m.Name, payload.UsedPercent, FormatBytes(event.Available))
+	case CleanFailed:
+		payload.Error = event.Err.Error()
+		payload.Text = fmt.Sprintf("Cleaning disk space on %s failed at %s used: %v",
+			m.Name, payload.UsedPercent, event.Err)
+	}
+	m.webhook.send(payload)
+}
+
+// send posts payload in the background, or drops it if
+// too many posts are already in flight.
+func (w *webhook) send(payload webhookPayload) {
+	select {
+	case w.slots <- struct{}{}:
+	default:
+		w.logger.Warn("too many webhook notifications in flight; dropping",
+			zap.String("event", payload.Event))
+		return
+	}
+	go func() {
+		defer func() { <-w.slots }()
+		if err := w.post(payload); err != nil {
+			w.logger.Error("sending webhook notification",
+				zap.String("event", payload.Event),
+				zap.Error(err))
+		}
+	}()
+}
+
+func (w *webhook) post(payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// leave out the URL, which may contain a secret
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+const (
+	webhookTimeout     = 10 * time.Second
+	webhookConcurrency = 4
+)